@@ -17,6 +17,7 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"net"
 	"regexp"
 	"strconv"
@@ -77,9 +78,11 @@ func listToOid(l []int) string {
 }
 
 type ScrapeResults struct {
-	pdus    []gosnmp.SnmpPDU
-	packets uint64
-	retries uint64
+	pdus []gosnmp.SnmpPDU
+	// When each of the pdus was received.
+	received []time.Time
+	packets  uint64
+	retries  uint64
 }
 
 func ScrapeTarget(ctx context.Context, target string, auth *config.Auth, module *config.Module, logger log.Logger, metrics Metrics) (ScrapeResults, error) {
@@ -99,14 +102,15 @@ func ScrapeTarget(ctx context.Context, target string, auth *config.Auth, module
 		snmp.AppOpts["c"] = true
 	}
 
-	var sent time.Time
+	var sent, recv time.Time
 	snmp.OnSent = func(x *gosnmp.GoSNMP) {
 		sent = time.Now()
 		metrics.SNMPPackets.Inc()
 		results.packets++
 	}
 	snmp.OnRecv = func(x *gosnmp.GoSNMP) {
-		metrics.SNMPDuration.Observe(time.Since(sent).Seconds())
+		recv = time.Now()
+		metrics.SNMPDuration.Observe(recv.Sub(sent).Seconds())
 	}
 	snmp.OnRetry = func(x *gosnmp.GoSNMP) {
 		metrics.SNMPRetries.Inc()
@@ -165,6 +169,35 @@ func ScrapeTarget(ctx context.Context, target string, auth *config.Auth, module
 		newGet = newCfg
 	}
 
+	// Get the scalars used as labels, the uptime used to extend counters and
+	// convert TimeTicks to timestamps, and the clock used for timestamps,
+	// unless they are already fetched.
+	extraGet := []string{}
+	for _, scalar := range module.ScalarLabels {
+		extraGet = append(extraGet, scalar.Oid)
+	}
+	uptimeOid := ""
+	if module.ExtendCounters != nil {
+		uptimeOid = module.ExtendCounters.UptimeOid
+	}
+	for _, metric := range module.Metrics {
+		if metric.Type == "TimeTicksAsTimestamp" {
+			uptimeOid = moduleUptimeOid(module)
+			break
+		}
+	}
+	if uptimeOid != "" {
+		extraGet = append(extraGet, uptimeOid)
+	}
+	if module.Timestamps != nil && module.Timestamps.Source == "device_clock" {
+		extraGet = append(extraGet, module.Timestamps.ClockOid)
+	}
+	for _, oid := range extraGet {
+		if !oidCovered(oid, newGet, newWalk) {
+			newGet = append(newGet[:len(newGet):len(newGet)], oid)
+		}
+	}
+
 	getOids := newGet
 	maxOids := int(module.WalkParams.MaxRepetitions)
 	// Max Repetition can be 0, maxOids cannot. SNMPv1 can only report one OID error per call.
@@ -205,18 +238,27 @@ func ScrapeTarget(ctx context.Context, target string, auth *config.Auth, module
 				continue
 			}
 			results.pdus = append(results.pdus, v)
+			results.received = append(results.received, recv)
 		}
 		getOids = getOids[oids:]
 	}
 
 	for _, subtree := range newWalk {
-		var pdus []gosnmp.SnmpPDU
+		var (
+			pdus     []gosnmp.SnmpPDU
+			received []time.Time
+		)
+		walkFn := func(pdu gosnmp.SnmpPDU) error {
+			pdus = append(pdus, pdu)
+			received = append(received, recv)
+			return nil
+		}
 		level.Debug(logger).Log("msg", "Walking subtree", "oid", subtree)
 		walkStart := time.Now()
 		if snmp.Version == gosnmp.Version1 {
-			pdus, err = snmp.WalkAll(subtree)
+			err = snmp.Walk(subtree, walkFn)
 		} else {
-			pdus, err = snmp.BulkWalkAll(subtree)
+			err = snmp.BulkWalk(subtree, walkFn)
 		}
 		if err != nil {
 			if err == context.Canceled {
@@ -228,6 +270,7 @@ func ScrapeTarget(ctx context.Context, target string, auth *config.Auth, module
 		level.Debug(logger).Log("msg", "Walk of subtree completed", "oid", subtree, "duration_seconds", time.Since(walkStart))
 
 		results.pdus = append(results.pdus, pdus...)
+		results.received = append(results.received, received...)
 	}
 	return results, nil
 }
@@ -255,7 +298,7 @@ func filterAllowedIndices(logger log.Logger, filter config.DynamicFilter, pdus [
 	for _, pdu := range pdus {
 		found := false
 		for _, val := range filter.Values {
-			snmpval := pduValueAsString(&pdu, "DisplayString", metrics)
+			snmpval := pduValueAsString(&pdu, "DisplayString", config.Formats{}, metrics)
 			level.Debug(logger).Log("config value", val, "snmp value", snmpval)
 
 			if regexp.MustCompile(val).MatchString(snmpval) {
@@ -350,6 +393,7 @@ type Metrics struct {
 	SNMPDuration           prometheus.Histogram
 	SNMPPackets            prometheus.Counter
 	SNMPRetries            prometheus.Counter
+	SNMPIndexDecodeErrors  *prometheus.CounterVec
 }
 
 type NamedModule struct {
@@ -412,10 +456,50 @@ func (c Collector) collect(ch chan<- prometheus.Metric, module *NamedModule) {
 		float64(len(results.pdus)))
 	oidToPdu := make(map[string]gosnmp.SnmpPDU, len(results.pdus))
 	for _, pdu := range results.pdus {
+		pdu, err := decodeOpaque(pdu)
+		if err != nil {
+			level.Debug(logger).Log("msg", "Error decoding Opaque value", "oid", pdu.Name, "err", err)
+		}
 		oidToPdu[pdu.Name[1:]] = pdu
 	}
 
+	moduleLabels := moduleSampleLabels(module.Module, oidToPdu, c.metrics)
 	metricTree := buildMetricTree(module.Metrics)
+	var computed *computedRows
+	if len(module.ComputedMetrics) > 0 {
+		computed = newComputedRows(module.Module)
+	}
+	types := newWireTypes(module.name)
+	timestamps := newSampleTimestamps(module.Module, results, oidToPdu, time.Now(), logger)
+	// The uptime tells wraps of extended counters from restarts, when
+	// counters were created, and when TimeTicksAsTimestamp events happened.
+	uptimeOid := moduleUptimeOid(module.Module)
+	var (
+		counters *targetCounters
+		now      = time.Now()
+	)
+	uptimePdu, haveUptime := oidToPdu[uptimeOid]
+	uptime := pduUptime(uptimePdu)
+	var uptimeForSamples *gosnmp.SnmpPDU
+	if haveUptime {
+		uptimeForSamples = &uptimePdu
+	}
+	if module.ExtendCounters != nil {
+		if haveUptime {
+			counters = counterStates.forTarget(counterKey{auth: c.authName, module: module.name, target: c.target}, now)
+		} else {
+			level.Debug(logger).Log("msg", "Uptime not returned, not extending counters", "oid", uptimeOid)
+		}
+	}
+	send := func(sample prometheus.Metric, ts time.Time) {
+		if haveUptime {
+			sample = createdCounter{Metric: sample, created: now.Add(-uptime)}
+		}
+		if !ts.IsZero() {
+			sample = prometheus.NewMetricWithTimestamp(ts, sample)
+		}
+		ch <- sample
+	}
 	// Look for metrics that match each pdu.
 PduLoop:
 	for oid, pdu := range oidToPdu {
@@ -429,14 +513,38 @@ PduLoop:
 			}
 			if head.metric != nil {
 				// Found a match.
-				samples := pduToSamples(oidList[i+1:], &pdu, head.metric, oidToPdu, logger, c.metrics)
+				types.add(head.metric, pdu.Type, logger)
+				if counters != nil && pdu.Type == gosnmp.Counter32 && head.metric.Combine == "" {
+					pdu = counters.extendPdu(oid, pdu, uptime, now)
+				}
+				samples, err := pduToSamples(oidList[i+1:], &pdu, head.metric, oidToPdu, uptimeForSamples, moduleLabels, module.MetricRelabelConfigs, logger, c.metrics)
+				if err != nil {
+					level.Debug(logger).Log("msg", "Skipping row with undecodable index", "metric", head.metric.Name, "oid", oid, "err", err)
+					c.metrics.SNMPIndexDecodeErrors.WithLabelValues(module.name, head.metric.Name).Inc()
+					break
+				}
+				if computed != nil {
+					computed.add(oidList[i+1:], &pdu, head.metric, oidToPdu)
+				}
+				ts := timestamps.forOid(oid)
 				for _, sample := range samples {
-					ch <- sample
+					send(sample, ts)
 				}
 				break
 			}
 		}
 	}
+	if module.MetricInfo {
+		for _, sample := range types.samples() {
+			ch <- sample
+		}
+	}
+	if computed != nil {
+		ts := timestamps.forOid("")
+		for _, sample := range computed.samples(module.Module, oidToPdu, moduleLabels, module.MetricRelabelConfigs, logger, c.metrics) {
+			send(sample, ts)
+		}
+	}
 	ch <- prometheus.MustNewConstMetric(
 		prometheus.NewDesc("snmp_scrape_duration_seconds", "Total SNMP time scrape took (walk and processing).", nil, moduleLabel),
 		prometheus.GaugeValue,
@@ -476,12 +584,12 @@ func (c Collector) Collect(ch chan<- prometheus.Metric) {
 
 func getPduValue(pdu *gosnmp.SnmpPDU) float64 {
 	switch pdu.Type {
-	case gosnmp.Counter64:
-		if *wrapCounters {
-			// Wrap by 2^53.
-			return float64(gosnmp.ToBigInt(pdu.Value).Uint64() % float64Mantissa)
-		}
-		return float64(gosnmp.ToBigInt(pdu.Value).Uint64())
+	case gosnmp.Counter64, opaqueCounter64:
+		return wrapCounter(gosnmp.ToBigInt(pdu.Value).Uint64())
+	case opaqueUnsigned64:
+		return float64(pdu.Value.(uint64))
+	case opaqueInteger64:
+		return float64(pdu.Value.(int64))
 	case gosnmp.OpaqueFloat:
 		return float64(pdu.Value.(float32))
 	case gosnmp.OpaqueDouble:
@@ -491,6 +599,35 @@ func getPduValue(pdu *gosnmp.SnmpPDU) float64 {
 	}
 }
 
+func wrapCounter(v uint64) float64 {
+	if *wrapCounters {
+		// Wrap by 2^53.
+		return float64(v % float64Mantissa)
+	}
+	return float64(v)
+}
+
+// The value of a metric, joining the high and low 32 bits of combined
+// metrics and converting TimeTicks to seconds. Returns false if the low
+// column is missing.
+func metricPduValue(indexOids []int, pdu *gosnmp.SnmpPDU, metric *config.Metric, oidToPdu map[string]gosnmp.SnmpPDU) (float64, bool) {
+	if metric.Combine == "" {
+		value := getPduValue(pdu)
+		if metric.Type == "TimeTicks" {
+			// Hundredths of a second.
+			value /= 100
+		}
+		return value, true
+	}
+	lowPdu, ok := oidToPdu[metric.Combine+"."+listToOid(indexOids)]
+	if !ok {
+		return 0, false
+	}
+	high := gosnmp.ToBigInt(pdu.Value).Uint64() & math.MaxUint32
+	low := gosnmp.ToBigInt(lowPdu.Value).Uint64() & math.MaxUint32
+	return wrapCounter(high<<32 | low), true
+}
+
 // parseDateAndTime extracts a UNIX timestamp from an RFC 2579 DateAndTime.
 func parseDateAndTime(pdu *gosnmp.SnmpPDU) (float64, error) {
 	var (
@@ -538,12 +675,57 @@ func parseDateAndTime(pdu *gosnmp.SnmpPDU) (float64, error) {
 	return float64(t.Unix()), nil
 }
 
-func pduToSamples(indexOids []int, pdu *gosnmp.SnmpPDU, metric *config.Metric, oidToPdu map[string]gosnmp.SnmpPDU, logger log.Logger, metrics Metrics) []prometheus.Metric {
-	var err error
+// Whether an oid is fetched by a get or is under a walked subtree.
+func oidCovered(oid string, get, walk []string) bool {
+	for _, g := range get {
+		if g == oid {
+			return true
+		}
+	}
+	for _, w := range walk {
+		if strings.HasPrefix(oid, w+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Labels added to all samples of a module, from its static labels and the
+// values of its scalar labels. Scalars missing from the results are left out.
+func moduleSampleLabels(module *config.Module, oidToPdu map[string]gosnmp.SnmpPDU, metrics Metrics) map[string]string {
+	labels := make(map[string]string, len(module.StaticLabels)+len(module.ScalarLabels))
+	for k, v := range module.StaticLabels {
+		labels[k] = v
+	}
+	for _, scalar := range module.ScalarLabels {
+		pdu, ok := oidToPdu[scalar.Oid]
+		if !ok {
+			continue
+		}
+		labels[scalar.Labelname] = pduValueAsLabel(&pdu, scalar.Type, scalar.DisplayHint, scalar.Encoding, scalar.Formats, metrics)
+	}
+	return labels
+}
+
+// Returns an error if the indexes can't be decoded, in which case the row is skipped.
+// The uptime PDU is that of the module, nil if not returned.
+// The module labels are added to all samples, then the relabel configs applied.
+func pduToSamples(indexOids []int, pdu *gosnmp.SnmpPDU, metric *config.Metric, oidToPdu map[string]gosnmp.SnmpPDU, uptimePdu *gosnmp.SnmpPDU, moduleLabels map[string]string, relabelConfigs []*config.RelabelConfig, logger log.Logger, metrics Metrics) ([]prometheus.Metric, error) {
 	// The part of the OID that is the indexes.
-	labels := indexesToLabels(indexOids, metric, oidToPdu, metrics)
+	labels, err := indexesToLabels(indexOids, metric, oidToPdu, metrics)
+	if err != nil {
+		return nil, err
+	}
+	// Collisions with the metric's own labels are rejected when loading the config.
+	for k, v := range moduleLabels {
+		labels[k] = v
+	}
 
-	value := getPduValue(pdu)
+	value, ok := metricPduValue(indexOids, pdu, metric, oidToPdu)
+	if !ok {
+		level.Debug(logger).Log("msg", "Missing low column of combined metric", "metric", metric.Name, "oid", metric.Combine+"."+listToOid(indexOids))
+		return []prometheus.Metric{}, nil
+	}
 
 	labelnames := make([]string, 0, len(labels)+1)
 	labelvalues := make([]string, 0, len(labels)+1)
@@ -558,21 +740,28 @@ func pduToSamples(indexOids []int, pdu *gosnmp.SnmpPDU, metric *config.Metric, o
 		t = prometheus.CounterValue
 	case "gauge":
 		t = prometheus.GaugeValue
-	case "Float", "Double":
+	case "Float", "Double", "TimeTicks":
+		t = prometheus.GaugeValue
+	case "TimeTicksAsTimestamp":
 		t = prometheus.GaugeValue
+		if uptimePdu == nil {
+			level.Debug(logger).Log("msg", "Uptime not returned, can't convert TimeTicks to a timestamp", "metric", metric.Name)
+			return []prometheus.Metric{}, nil
+		}
+		value = timeTicksAsTimestamp(uint32(value), pduUptimeTicks(*uptimePdu), time.Now())
 	case "DateAndTime":
 		t = prometheus.GaugeValue
 		value, err = parseDateAndTime(pdu)
 		if err != nil {
 			level.Debug(logger).Log("msg", "Error parsing DateAndTime", "err", err)
-			return []prometheus.Metric{}
+			return []prometheus.Metric{}, nil
 		}
 	case "EnumAsInfo":
-		return enumAsInfo(metric, int(value), labelnames, labelvalues)
+		return enumAsInfo(metric, int(value), labelnames, labelvalues, relabelConfigs), nil
 	case "EnumAsStateSet":
-		return enumAsStateSet(metric, int(value), labelnames, labelvalues)
+		return enumAsStateSet(metric, int(value), labelnames, labelvalues, relabelConfigs), nil
 	case "Bits":
-		return bits(metric, pdu.Value, labelnames, labelvalues)
+		return bits(metric, pdu.Value, labelnames, labelvalues, relabelConfigs), nil
 	default:
 		// It's some form of string.
 		t = prometheus.GaugeValue
@@ -597,13 +786,13 @@ func pduToSamples(indexOids []int, pdu *gosnmp.SnmpPDU, metric *config.Metric, o
 		}
 
 		if len(metric.RegexpExtracts) > 0 {
-			return applyRegexExtracts(metric, pduValueAsString(pdu, metricType, metrics), labelnames, labelvalues, logger)
+			return applyRegexExtracts(metric, pduValueAsLabel(pdu, metricType, metric.DisplayHint, metric.Encoding, metric.Formats, metrics), labelnames, labelvalues, relabelConfigs, logger), nil
 		}
 		// For strings we put the value as a label with the same name as the metric.
 		// If the name is already an index, we do not need to set it again.
 		if _, ok := labels[metric.Name]; !ok {
 			labelnames = append(labelnames, metric.Name)
-			labelvalues = append(labelvalues, pduValueAsString(pdu, metricType, metrics))
+			labelvalues = append(labelvalues, pduValueAsLabel(pdu, metricType, metric.DisplayHint, metric.Encoding, metric.Formats, metrics))
 		}
 	}
 
@@ -612,17 +801,20 @@ func pduToSamples(indexOids []int, pdu *gosnmp.SnmpPDU, metric *config.Metric, o
 	}
 	value += metric.Offset
 
-	sample, err := prometheus.NewConstMetric(prometheus.NewDesc(metric.Name, metric.Help, labelnames, nil),
-		t, value, labelvalues...)
+	sample, err := newConstMetric(metric.Name, metric.Help, labelnames, labelvalues, t, value, relabelConfigs)
 	if err != nil {
 		sample = prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "Error calling NewConstMetric", nil, nil),
 			fmt.Errorf("error for metric %s with labels %v from indexOids %v: %v", metric.Name, labelvalues, indexOids, err))
 	}
+	if sample == nil {
+		// Dropped by relabeling.
+		return []prometheus.Metric{}, nil
+	}
 
-	return []prometheus.Metric{sample}
+	return []prometheus.Metric{sample}, nil
 }
 
-func applyRegexExtracts(metric *config.Metric, pduValue string, labelnames, labelvalues []string, logger log.Logger) []prometheus.Metric {
+func applyRegexExtracts(metric *config.Metric, pduValue string, labelnames, labelvalues []string, relabelConfigs []*config.RelabelConfig, logger log.Logger) []prometheus.Metric {
 	results := []prometheus.Metric{}
 	for name, strMetricSlice := range metric.RegexpExtracts {
 		for _, strMetric := range strMetricSlice {
@@ -637,20 +829,22 @@ func applyRegexExtracts(metric *config.Metric, pduValue string, labelnames, labe
 				level.Debug(logger).Log("msg", "Error parsing float64 from value", "metric", metric.Name, "value", pduValue, "regex", strMetric.Regex.String(), "extracted_value", res)
 				continue
 			}
-			newMetric, err := prometheus.NewConstMetric(prometheus.NewDesc(metric.Name+name, metric.Help+" (regex extracted)", labelnames, nil),
-				prometheus.GaugeValue, v, labelvalues...)
+			newMetric, err := newConstMetric(metric.Name+name, metric.Help+" (regex extracted)", labelnames, labelvalues,
+				prometheus.GaugeValue, v, relabelConfigs)
 			if err != nil {
 				newMetric = prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "Error calling NewConstMetric for regex_extract", nil, nil),
 					fmt.Errorf("error for metric %s with labels %v: %v", metric.Name+name, labelvalues, err))
 			}
-			results = append(results, newMetric)
+			if newMetric != nil {
+				results = append(results, newMetric)
+			}
 			break
 		}
 	}
 	return results
 }
 
-func enumAsInfo(metric *config.Metric, value int, labelnames, labelvalues []string) []prometheus.Metric {
+func enumAsInfo(metric *config.Metric, value int, labelnames, labelvalues []string, relabelConfigs []*config.RelabelConfig) []prometheus.Metric {
 	// Lookup enum, default to the value.
 	state, ok := metric.EnumValues[int(value)]
 	if !ok {
@@ -659,16 +853,19 @@ func enumAsInfo(metric *config.Metric, value int, labelnames, labelvalues []stri
 	labelnames = append(labelnames, metric.Name)
 	labelvalues = append(labelvalues, state)
 
-	newMetric, err := prometheus.NewConstMetric(prometheus.NewDesc(metric.Name+"_info", metric.Help+" (EnumAsInfo)", labelnames, nil),
-		prometheus.GaugeValue, 1.0, labelvalues...)
+	newMetric, err := newConstMetric(metric.Name+"_info", metric.Help+" (EnumAsInfo)", labelnames, labelvalues,
+		prometheus.GaugeValue, 1.0, relabelConfigs)
 	if err != nil {
 		newMetric = prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "Error calling NewConstMetric for EnumAsInfo", nil, nil),
 			fmt.Errorf("error for metric %s with labels %v: %v", metric.Name, labelvalues, err))
 	}
+	if newMetric == nil {
+		return []prometheus.Metric{}
+	}
 	return []prometheus.Metric{newMetric}
 }
 
-func enumAsStateSet(metric *config.Metric, value int, labelnames, labelvalues []string) []prometheus.Metric {
+func enumAsStateSet(metric *config.Metric, value int, labelnames, labelvalues []string, relabelConfigs []*config.RelabelConfig) []prometheus.Metric {
 	labelnames = append(labelnames, metric.Name)
 	results := []prometheus.Metric{}
 
@@ -677,30 +874,34 @@ func enumAsStateSet(metric *config.Metric, value int, labelnames, labelvalues []
 		// Fallback to using the value.
 		state = strconv.Itoa(value)
 	}
-	newMetric, err := prometheus.NewConstMetric(prometheus.NewDesc(metric.Name, metric.Help+" (EnumAsStateSet)", labelnames, nil),
-		prometheus.GaugeValue, 1.0, append(labelvalues, state)...)
+	newMetric, err := newConstMetric(metric.Name, metric.Help+" (EnumAsStateSet)", labelnames, append(labelvalues, state),
+		prometheus.GaugeValue, 1.0, relabelConfigs)
 	if err != nil {
 		newMetric = prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "Error calling NewConstMetric for EnumAsStateSet", nil, nil),
 			fmt.Errorf("error for metric %s with labels %v: %v", metric.Name, labelvalues, err))
 	}
-	results = append(results, newMetric)
+	if newMetric != nil {
+		results = append(results, newMetric)
+	}
 
 	for k, v := range metric.EnumValues {
 		if k == value {
 			continue
 		}
-		newMetric, err := prometheus.NewConstMetric(prometheus.NewDesc(metric.Name, metric.Help+" (EnumAsStateSet)", labelnames, nil),
-			prometheus.GaugeValue, 0.0, append(labelvalues, v)...)
+		newMetric, err := newConstMetric(metric.Name, metric.Help+" (EnumAsStateSet)", labelnames, append(labelvalues, v),
+			prometheus.GaugeValue, 0.0, relabelConfigs)
 		if err != nil {
 			newMetric = prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "Error calling NewConstMetric for EnumAsStateSet", nil, nil),
 				fmt.Errorf("error for metric %s with labels %v: %v", metric.Name, labelvalues, err))
 		}
-		results = append(results, newMetric)
+		if newMetric != nil {
+			results = append(results, newMetric)
+		}
 	}
 	return results
 }
 
-func bits(metric *config.Metric, value interface{}, labelnames, labelvalues []string) []prometheus.Metric {
+func bits(metric *config.Metric, value interface{}, labelnames, labelvalues []string, relabelConfigs []*config.RelabelConfig) []prometheus.Metric {
 	bytes, ok := value.([]byte)
 	if !ok {
 		return []prometheus.Metric{prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "BITS type was not a BISTRING on the wire.", nil, nil),
@@ -717,37 +918,61 @@ func bits(metric *config.Metric, value interface{}, labelnames, labelvalues []st
 				bit = 1.0
 			}
 		}
-		newMetric, err := prometheus.NewConstMetric(prometheus.NewDesc(metric.Name, metric.Help+" (Bits)", labelnames, nil),
-			prometheus.GaugeValue, bit, append(labelvalues, v)...)
+		newMetric, err := newConstMetric(metric.Name, metric.Help+" (Bits)", labelnames, append(labelvalues, v),
+			prometheus.GaugeValue, bit, relabelConfigs)
 		if err != nil {
 			newMetric = prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "Error calling NewConstMetric for Bits", nil, nil),
 				fmt.Errorf("error for metric %s with labels %v: %v", metric.Name, labelvalues, err))
 		}
-		results = append(results, newMetric)
+		if newMetric != nil {
+			results = append(results, newMetric)
+		}
 	}
 	return results
 }
 
-// Right pad oid with zeros, and split at the given point.
-// Some routers exclude trailing 0s in responses.
-func splitOid(oid []int, count int) ([]int, []int) {
-	head := make([]int, count)
-	tail := []int{}
-	for i, v := range oid {
-		if i < count {
-			head[i] = v
-		} else {
-			tail = append(tail, v)
+// Split oid at the given point.
+func splitOid(oid []int, count int) ([]int, []int, error) {
+	if count < 0 || len(oid) < count {
+		return nil, nil, fmt.Errorf("index needs %d sub-identifiers, but only %d are left", count, len(oid))
+	}
+	head := append([]int{}, oid[:count]...)
+	tail := append([]int{}, oid[count:]...)
+	return head, tail, nil
+}
+
+// Split off a variable length index value. Unless the length is fixed or the
+// index is implied, it comes from the first oid.
+//
+// Returns the oids that were used, the content and the oids left over.
+func splitLengthOid(oid []int, fixedSize int, implied bool) ([]int, []int, []int, error) {
+	var subOid []int
+	length := fixedSize
+	if implied {
+		length = len(oid)
+	}
+	if length == 0 {
+		var err error
+		subOid, oid, err = splitOid(oid, 1)
+		if err != nil {
+			return nil, nil, nil, err
 		}
+		length = subOid[0]
 	}
-	return head, tail
+	content, oid, err := splitOid(oid, length)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return append(subOid, content...), content, oid, nil
 }
 
 // This mirrors decodeValue in gosnmp's helper.go.
-func pduValueAsString(pdu *gosnmp.SnmpPDU, typ string, metrics Metrics) string {
+func pduValueAsString(pdu *gosnmp.SnmpPDU, typ string, formats config.Formats, metrics Metrics) string {
 	switch pdu.Value.(type) {
 	case int:
 		return strconv.Itoa(pdu.Value.(int))
+	case int64:
+		return strconv.FormatInt(pdu.Value.(int64), 10)
 	case uint:
 		return strconv.FormatUint(uint64(pdu.Value.(uint)), 10)
 	case uint64:
@@ -776,7 +1001,12 @@ func pduValueAsString(pdu *gosnmp.SnmpPDU, typ string, metrics Metrics) string {
 			// Prepend the length, as it is explicit in an index.
 			parts = append([]int{len(pdu.Value.([]byte))}, parts...)
 		}
-		str, _, _ := indexOidsAsString(parts, typ, 0, false, nil)
+		str, _, _, err := indexOidsAsString(parts, typ, 0, false, nil, formats)
+		if err != nil {
+			// The value doesn't fit the type, such as a truncated address.
+			parts = append([]int{len(parts)}, parts...)
+			str, _, _, _ = indexOidsAsString(parts, "OctetString", 0, false, nil, formats)
+		}
 		return strings.ToValidUTF8(str, "�")
 	case nil:
 		return ""
@@ -787,106 +1017,178 @@ func pduValueAsString(pdu *gosnmp.SnmpPDU, typ string, metrics Metrics) string {
 	}
 }
 
+// Render bytes as hex in the given format.
+func formatBytes(value []int, format config.ByteFormat) string {
+	digits := "%02X"
+	if format.Lowercase {
+		digits = "%02x"
+	}
+	var b strings.Builder
+	b.WriteString(format.Prefix)
+	for i, o := range value {
+		if format.Group != 0 && i != 0 && i%format.Group == 0 {
+			b.WriteString(format.Separator)
+		}
+		fmt.Fprintf(&b, digits, o)
+	}
+	return b.String()
+}
+
 // Convert oids to a string index value.
 //
 // Returns the string, the oids that were used and the oids left over.
-func indexOidsAsString(indexOids []int, typ string, fixedSize int, implied bool, enumValues map[int]string) (string, []int, []int) {
+func indexOidsAsString(indexOids []int, typ string, fixedSize int, implied bool, enumValues map[int]string, formats config.Formats) (string, []int, []int, error) {
 	if typeMapping, ok := combinedTypeMapping[typ]; ok {
-		subOid, valueOids := splitOid(indexOids, 2)
+		size := 2
 		if typ == "InetAddressMissingSize" {
 			// The size of the main index value is missing.
-			subOid, valueOids = splitOid(indexOids, 1)
+			size = 1
+		}
+		subOid, valueOids, err := splitOid(indexOids, size)
+		if err != nil {
+			return "", nil, nil, err
 		}
-		var str string
-		var used, remaining []int
 		if t, ok := typeMapping[subOid[0]]; ok {
-			str, used, remaining = indexOidsAsString(valueOids, t, 0, false, enumValues)
-			return str, append(subOid, used...), remaining
+			str, used, remaining, err := indexOidsAsString(valueOids, t, 0, false, enumValues, formats)
+			return str, append(subOid, used...), remaining, err
 		}
 		if typ == "InetAddressMissingSize" {
 			// We don't know the size, so pass everything remaining.
-			return indexOidsAsString(indexOids, "OctetString", 0, true, enumValues)
+			return indexOidsAsString(indexOids, "OctetString", 0, true, enumValues, formats)
 		}
 		// The 2nd oid is the length.
-		return indexOidsAsString(indexOids, "OctetString", subOid[1]+2, false, enumValues)
+		return indexOidsAsString(indexOids, "OctetString", subOid[1]+2, false, enumValues, formats)
 	}
 
 	switch typ {
-	case "Integer32", "Integer", "gauge", "counter":
+	case "Integer32", "Integer", "gauge", "counter", "Unsigned32", "TimeTicks":
 		// Extract the oid for this index, and keep the remainder for the next index.
-		subOid, indexOids := splitOid(indexOids, 1)
-		return fmt.Sprintf("%d", subOid[0]), subOid, indexOids
+		subOid, indexOids, err := splitOid(indexOids, 1)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return fmt.Sprintf("%d", subOid[0]), subOid, indexOids, nil
 	case "PhysAddress48":
-		subOid, indexOids := splitOid(indexOids, 6)
-		parts := make([]string, 6)
-		for i, o := range subOid {
-			parts[i] = fmt.Sprintf("%02X", o)
-		}
-		return strings.Join(parts, ":"), subOid, indexOids
-	case "OctetString":
-		var subOid []int
-		// The length of fixed size indexes come from the MIB.
-		// For varying size, we read it from the first oid.
-		length := fixedSize
-		if implied {
-			length = len(indexOids)
-		}
-		if length == 0 {
-			subOid, indexOids = splitOid(indexOids, 1)
-			length = subOid[0]
-		}
-		content, indexOids := splitOid(indexOids, length)
-		subOid = append(subOid, content...)
-		parts := make([]byte, length)
-		for i, o := range content {
-			parts[i] = byte(o)
-		}
-		if len(parts) == 0 {
-			return "", subOid, indexOids
-		}
-		return fmt.Sprintf("0x%X", string(parts)), subOid, indexOids
-	case "DisplayString":
-		var subOid []int
-		length := fixedSize
-		if implied {
-			length = len(indexOids)
-		}
-		if length == 0 {
-			subOid, indexOids = splitOid(indexOids, 1)
-			length = subOid[0]
-		}
-		content, indexOids := splitOid(indexOids, length)
-		subOid = append(subOid, content...)
-		parts := make([]byte, length)
-		for i, o := range content {
-			parts[i] = byte(o)
-		}
-		// ASCII, so can convert staight to utf-8.
-		return string(parts), subOid, indexOids
-	case "InetAddressIPv4":
-		subOid, indexOids := splitOid(indexOids, 4)
+		subOid, indexOids, err := splitOid(indexOids, 6)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		format, ok := config.MACFormats[formats.MACFormat]
+		if !ok {
+			format = config.MACFormats["colon"]
+		}
+		return formatBytes(subOid, format), subOid, indexOids, nil
+	case "OctetString", "DisplayString":
+		subOid, content, indexOids, err := splitLengthOid(indexOids, fixedSize, implied)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		if typ == "DisplayString" {
+			parts := make([]byte, len(content))
+			for i, o := range content {
+				parts[i] = byte(o)
+			}
+			// ASCII, so can convert staight to utf-8.
+			return string(parts), subOid, indexOids, nil
+		}
+		if len(content) == 0 {
+			return "", subOid, indexOids, nil
+		}
+		format, ok := config.HexFormats[formats.HexFormat]
+		if !ok {
+			format = config.HexFormats["prefixed"]
+		}
+		return formatBytes(content, format), subOid, indexOids, nil
+	case "InetAddressIPv4", "IpAddress":
+		subOid, indexOids, err := splitOid(indexOids, 4)
+		if err != nil {
+			return "", nil, nil, err
+		}
 		parts := make([]string, 4)
 		for i, o := range subOid {
 			parts[i] = strconv.Itoa(o)
 		}
-		return strings.Join(parts, "."), subOid, indexOids
+		return strings.Join(parts, "."), subOid, indexOids, nil
 	case "InetAddressIPv6":
-		subOid, indexOids := splitOid(indexOids, 16)
+		subOid, indexOids, err := splitOid(indexOids, 16)
+		if err != nil {
+			return "", nil, nil, err
+		}
 		parts := make([]interface{}, 16)
 		for i, o := range subOid {
 			parts[i] = o
 		}
-		return fmt.Sprintf("%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X", parts...), subOid, indexOids
+		return fmt.Sprintf("%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X:%02X%02X", parts...), subOid, indexOids, nil
 	case "EnumAsInfo":
-		subOid, indexOids := splitOid(indexOids, 1)
+		subOid, indexOids, err := splitOid(indexOids, 1)
+		if err != nil {
+			return "", nil, nil, err
+		}
 		value, ok := enumValues[subOid[0]]
 		if ok {
-			return value, subOid, indexOids
+			return value, subOid, indexOids, nil
 		}
-		return fmt.Sprintf("%d", subOid[0]), subOid, indexOids
+		return fmt.Sprintf("%d", subOid[0]), subOid, indexOids, nil
+	case "ObjectIdentifier":
+		subOid, content, indexOids, err := splitLengthOid(indexOids, fixedSize, implied)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return listToOid(content), subOid, indexOids, nil
+	default:
+		// Unknown type, so we can't tell where it ends. Use all that's left.
+		return listToOid(indexOids), indexOids, []int{}, nil
+	}
+}
+
+// Convert a PDU value to the oids of an index with that value, the reverse
+// of indexOidsAsString. Strings are prefixed with their length, unless the
+// index has a fixed size or is implied.
+func pduValueAsOids(pdu *gosnmp.SnmpPDU, typ string, fixedSize int, implied bool) ([]int, error) {
+	var value []byte
+	switch v := pdu.Value.(type) {
+	case int, int32, int64, uint, uint32, uint64:
+		n := gosnmp.ToBigInt(v)
+		if n.Sign() < 0 || !n.IsInt64() || n.Int64() > math.MaxUint32 {
+			return nil, fmt.Errorf("value %s out of range for an index", n)
+		}
+		return []int{int(n.Int64())}, nil
+	case string:
+		switch pdu.Type {
+		case gosnmp.ObjectIdentifier:
+			oids := oidToList(strings.TrimPrefix(v, "."))
+			if implied || fixedSize != 0 {
+				return oids, nil
+			}
+			return append([]int{len(oids)}, oids...), nil
+		case gosnmp.IPAddress:
+			ip := net.ParseIP(v).To4()
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", v)
+			}
+			value = ip
+			typ = "IpAddress"
+		default:
+			value = []byte(v)
+		}
+	case []byte:
+		value = v
 	default:
-		panic(fmt.Sprintf("Unknown index type %s", typ))
+		return nil, fmt.Errorf("unsupported value type %T for an index", v)
 	}
+	oids := make([]int, 0, len(value)+1)
+	switch typ {
+	case "PhysAddress48", "InetAddressIPv4", "InetAddressIPv6", "IpAddress":
+		// Always a fixed size.
+	default:
+		if !implied && fixedSize == 0 {
+			oids = append(oids, len(value))
+		}
+	}
+	for _, o := range value {
+		oids = append(oids, int(o))
+	}
+	return oids, nil
 }
 
 func getPrevOid(oid string) string {
@@ -896,13 +1198,32 @@ func getPrevOid(oid string) string {
 	return strings.Join(oids, ".")
 }
 
-func indexesToLabels(indexOids []int, metric *config.Metric, oidToPdu map[string]gosnmp.SnmpPDU, metrics Metrics) map[string]string {
+func indexesToLabels(indexOids []int, metric *config.Metric, oidToPdu map[string]gosnmp.SnmpPDU, metrics Metrics) (map[string]string, error) {
 	labels := map[string]string{}
 	labelOids := map[string][]int{}
 
+	// Labels set by lookups.
+	lookedUp := map[string]struct{}{}
+	for _, lookup := range metric.Lookups {
+		lookedUp[lookup.Labelname] = struct{}{}
+	}
+
 	// Covert indexes to useful strings.
 	for _, index := range metric.Indexes {
-		str, subOid, remainingOids := indexOidsAsString(indexOids, index.Type, index.FixedSize, index.Implied, index.EnumValues)
+		str, subOid, remainingOids, err := indexOidsAsString(indexOids, index.Type, index.FixedSize, index.Implied, index.EnumValues, index.Formats)
+		if err != nil {
+			if _, ok := lookedUp[index.Labelname]; ok && len(indexOids) == 0 {
+				// The generator adds the result of a chained lookup as an
+				// index past the end of the oid, the lookup fills it in.
+				continue
+			}
+			return nil, fmt.Errorf("error decoding index %s of type %s: %s", index.Labelname, index.Type, err)
+		}
+		if index.DisplayHint != "" {
+			if hinted, err := indexDisplayHint(index, subOid); err == nil {
+				str = hinted
+			}
+		}
 		// The labelvalue is the text form of the index oids.
 		labels[index.Labelname] = str
 		// Save its oid in case we need it for lookups.
@@ -917,18 +1238,29 @@ func indexesToLabels(indexOids []int, metric *config.Metric, oidToPdu map[string
 			delete(labels, lookup.Labelname)
 			continue
 		}
-		oid := lookup.Oid
+		lookupOids := []int{}
 		for _, label := range lookup.Labels {
-			oid = fmt.Sprintf("%s.%s", oid, listToOid(labelOids[label]))
+			lookupOids = append(lookupOids, labelOids[label]...)
+		}
+		if lookup.Via != nil {
+			// Index the lookup by the value of the intermediate column.
+			viaPdu, ok := oidToPdu[fmt.Sprintf("%s.%s", lookup.Via.Oid, listToOid(lookupOids))]
+			if !ok {
+				labels[lookup.Labelname] = ""
+				continue
+			}
+			var err error
+			lookupOids, err = pduValueAsOids(&viaPdu, lookup.Via.Type, lookup.Via.FixedSize, lookup.Via.Implied)
+			if err != nil {
+				labels[lookup.Labelname] = ""
+				continue
+			}
 		}
-		if pdu, ok := oidToPdu[oid]; ok {
+		if pdu, ok := oidToPdu[fmt.Sprintf("%s.%s", lookup.Oid, listToOid(lookupOids))]; ok {
 			t := lookup.Type
 			if typeMapping, ok := combinedTypeMapping[lookup.Type]; ok {
 				// Lookup associated sub type in previous object.
-				prevOid := getPrevOid(lookup.Oid)
-				for _, label := range lookup.Labels {
-					prevOid = fmt.Sprintf("%s.%s", prevOid, listToOid(labelOids[label]))
-				}
+				prevOid := fmt.Sprintf("%s.%s", getPrevOid(lookup.Oid), listToOid(lookupOids))
 				if prevPdu, ok := oidToPdu[prevOid]; ok {
 					val := int(getPduValue(&prevPdu))
 					if ty, ok := typeMapping[val]; ok {
@@ -936,12 +1268,17 @@ func indexesToLabels(indexOids []int, metric *config.Metric, oidToPdu map[string
 					}
 				}
 			}
-			labels[lookup.Labelname] = pduValueAsString(&pdu, t, metrics)
-			labelOids[lookup.Labelname] = []int{int(gosnmp.ToBigInt(pdu.Value).Int64())}
+			labels[lookup.Labelname] = pduValueAsLabel(&pdu, t, lookup.DisplayHint, lookup.Encoding, lookup.Formats, metrics)
+			// Keep the full index encoding, so further lookups can use it.
+			if oids, err := pduValueAsOids(&pdu, t, 0, false); err == nil {
+				labelOids[lookup.Labelname] = oids
+			} else {
+				delete(labelOids, lookup.Labelname)
+			}
 		} else {
 			labels[lookup.Labelname] = ""
 		}
 	}
 
-	return labels
+	return labels, nil
 }
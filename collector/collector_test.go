@@ -35,6 +35,7 @@ func TestPduToSample(t *testing.T) {
 		indexOids       []int
 		metric          *config.Metric
 		oidToPdu        map[string]gosnmp.SnmpPDU
+		moduleLabels    map[string]string
 		expectedMetrics []string
 		shouldErr       bool
 	}{
@@ -567,10 +568,103 @@ func TestPduToSample(t *testing.T) {
 				`Desc{fqName: "test_metric", help: "Help string (Bits)", constLabels: {}, variableLabels: {test_metric}} label:{name:"test_metric" value:"missing"} gauge:{value:0}`,
 			},
 		},
+		{
+			pdu: &gosnmp.SnmpPDU{
+				Name:  "1.1.1.1.1.0",
+				Type:  gosnmp.Counter32,
+				Value: 3,
+			},
+			indexOids: []int{},
+			metric: &config.Metric{
+				Name: "test_metric",
+				Oid:  "1.1.1.1.1",
+				Type: "counter",
+				Help: "Help string",
+			},
+			moduleLabels: map[string]string{"sysName": "router1"},
+			expectedMetrics: []string{
+				`Desc{fqName: "test_metric", help: "Help string", constLabels: {}, variableLabels: {sysName}} label:{name:"sysName" value:"router1"} counter:{value:3}`,
+			},
+		},
+		{
+			pdu: &gosnmp.SnmpPDU{
+				Name:  "1.1.1.1.1.0",
+				Type:  gosnmp.Gauge32,
+				Value: uint(1),
+			},
+			indexOids: []int{0},
+			metric: &config.Metric{
+				Name:    "test_metric",
+				Oid:     "1.1.1.1.1",
+				Type:    "counter",
+				Help:    "Help string",
+				Combine: "1.1.1.1.2",
+			},
+			oidToPdu: map[string]gosnmp.SnmpPDU{
+				"1.1.1.1.2.0": {Name: "1.1.1.1.2.0", Type: gosnmp.Gauge32, Value: uint(5)},
+			},
+			expectedMetrics: []string{
+				`Desc{fqName: "test_metric", help: "Help string", constLabels: {}, variableLabels: {}} counter:{value:4.294967301e+09}`,
+			},
+		},
+		{
+			// Missing low column.
+			pdu: &gosnmp.SnmpPDU{
+				Name:  "1.1.1.1.1.0",
+				Type:  gosnmp.Gauge32,
+				Value: uint(1),
+			},
+			indexOids: []int{},
+			metric: &config.Metric{
+				Name:    "test_metric",
+				Oid:     "1.1.1.1.1",
+				Type:    "counter",
+				Help:    "Help string",
+				Combine: "1.1.1.1.2",
+			},
+			expectedMetrics: []string{},
+		},
+		{
+			pdu: &gosnmp.SnmpPDU{
+				Name:  "1.3.6.1.2.1.1.3.0",
+				Type:  gosnmp.TimeTicks,
+				Value: uint32(123456),
+			},
+			indexOids: []int{},
+			metric: &config.Metric{
+				Name: "sysUpTime",
+				Oid:  "1.3.6.1.2.1.1.3",
+				Type: "TimeTicks",
+				Help: "Help string",
+			},
+			expectedMetrics: []string{
+				`Desc{fqName: "sysUpTime", help: "Help string", constLabels: {}, variableLabels: {}} gauge:{value:1234.56}`,
+			},
+		},
+		{
+			// No sysUpTime to convert to a timestamp with.
+			pdu: &gosnmp.SnmpPDU{
+				Name:  "1.3.6.1.2.1.2.2.1.9.1",
+				Type:  gosnmp.TimeTicks,
+				Value: uint32(100),
+			},
+			indexOids: []int{1},
+			metric: &config.Metric{
+				Name: "ifLastChange",
+				Oid:  "1.3.6.1.2.1.2.2.1.9",
+				Type: "TimeTicksAsTimestamp",
+				Help: "Help string",
+			},
+			oidToPdu:        map[string]gosnmp.SnmpPDU{},
+			expectedMetrics: []string{},
+		},
 	}
 
 	for _, c := range cases {
-		metrics := pduToSamples(c.indexOids, c.pdu, c.metric, c.oidToPdu, log.NewNopLogger(), Metrics{})
+		metrics, err := pduToSamples(c.indexOids, c.pdu, c.metric, c.oidToPdu, nil, c.moduleLabels, nil, log.NewNopLogger(), Metrics{})
+		if err != nil {
+			t.Fatalf("Unexpected error from pduToSamples: %v", err)
+		}
 		metric := &io_prometheus_client.Metric{}
 		expected := map[string]struct{}{}
 		for _, e := range c.expectedMetrics {
@@ -643,6 +737,37 @@ func TestGetPduLargeValue(t *testing.T) {
 	}
 }
 
+func TestMetricPduValueCombined(t *testing.T) {
+	_, err := kingpin.CommandLine.Parse([]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 19007199254740992 split into its high and low 32 bits.
+	metric := &config.Metric{Name: "test_metric", Oid: "1.1", Type: "counter", Combine: "1.2"}
+	high := &gosnmp.SnmpPDU{Name: "1.1.7", Type: gosnmp.Gauge32, Value: uint(4425458)}
+	oidToPdu := map[string]gosnmp.SnmpPDU{
+		"1.2.7": {Name: "1.2.7", Type: gosnmp.Gauge32, Value: uint(1874919424)},
+	}
+	value, ok := metricPduValue([]int{7}, high, metric, oidToPdu)
+	if !ok || value != 992800745259008.0 {
+		t.Fatalf("Got incorrect counter wrapping for combined metric: %v", value)
+	}
+
+	_, err = kingpin.CommandLine.Parse([]string{"--no-snmp.wrap-large-counters"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, ok = metricPduValue([]int{7}, high, metric, oidToPdu)
+	if !ok || value != 19007199254740990.0 {
+		t.Fatalf("Got incorrect rounded float for combined metric: %v", value)
+	}
+
+	if _, ok := metricPduValue([]int{8}, high, metric, oidToPdu); ok {
+		t.Fatalf("Expected missing low column to be reported")
+	}
+}
+
 func TestOidToList(t *testing.T) {
 	cases := []struct {
 		oid    string
@@ -671,6 +796,7 @@ func TestSplitOid(t *testing.T) {
 		count      int
 		resultHead []int
 		resultTail []int
+		err        bool
 	}{
 		{
 			oid:        []int{1, 2, 3, 4},
@@ -680,30 +806,88 @@ func TestSplitOid(t *testing.T) {
 		},
 		{
 			oid:        []int{1, 2},
-			count:      4,
-			resultHead: []int{1, 2, 0, 0},
+			count:      2,
+			resultHead: []int{1, 2},
 			resultTail: []int{},
 		},
 		{
-			oid:        []int{},
-			count:      2,
-			resultHead: []int{0, 0},
-			resultTail: []int{},
+			oid:   []int{1, 2},
+			count: 4,
+			err:   true,
+		},
+		{
+			oid:   []int{},
+			count: 2,
+			err:   true,
+		},
+		{
+			oid:   []int{1, 2},
+			count: -1,
+			err:   true,
 		},
 	}
 	for _, c := range cases {
-		head, tail := splitOid(c.oid, c.count)
+		head, tail, err := splitOid(c.oid, c.count)
+		if c.err {
+			if err == nil {
+				t.Errorf("splitOid(%v, %d): expected error, got [%v, %v]", c.oid, c.count, head, tail)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitOid(%v, %d): unexpected error: %v", c.oid, c.count, err)
+			continue
+		}
 		if !reflect.DeepEqual(head, c.resultHead) || !reflect.DeepEqual(tail, c.resultTail) {
 			t.Errorf("splitOid(%v, %d): got [%v, %v], want [%v, %v]", c.oid, c.count, head, tail, c.resultHead, c.resultTail)
 		}
 	}
 }
 
+func TestPduValueAsOids(t *testing.T) {
+	cases := []struct {
+		pdu       *gosnmp.SnmpPDU
+		typ       string
+		fixedSize int
+		implied   bool
+		result    []int
+		err       bool
+	}{
+		{pdu: &gosnmp.SnmpPDU{Value: 5}, typ: "gauge", result: []int{5}},
+		{pdu: &gosnmp.SnmpPDU{Value: uint(4294967295)}, typ: "gauge", result: []int{4294967295}},
+		{pdu: &gosnmp.SnmpPDU{Value: -1}, typ: "gauge", err: true},
+		{pdu: &gosnmp.SnmpPDU{Value: []byte("ab")}, typ: "DisplayString", result: []int{2, 97, 98}},
+		{pdu: &gosnmp.SnmpPDU{Value: []byte("ab")}, typ: "DisplayString", implied: true, result: []int{97, 98}},
+		{pdu: &gosnmp.SnmpPDU{Value: []byte("ab")}, typ: "OctetString", fixedSize: 2, result: []int{97, 98}},
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{0, 1, 2, 3, 4, 5}}, typ: "PhysAddress48", result: []int{0, 1, 2, 3, 4, 5}},
+		{pdu: &gosnmp.SnmpPDU{Value: "10.0.0.1", Type: gosnmp.IPAddress}, typ: "InetAddressIPv4", result: []int{10, 0, 0, 1}},
+		{pdu: &gosnmp.SnmpPDU{Value: ".1.3.6", Type: gosnmp.ObjectIdentifier}, typ: "ObjectIdentifier", result: []int{3, 1, 3, 6}},
+		{pdu: &gosnmp.SnmpPDU{Value: nil}, typ: "gauge", err: true},
+	}
+	for _, c := range cases {
+		got, err := pduValueAsOids(c.pdu, c.typ, c.fixedSize, c.implied)
+		if c.err {
+			if err == nil {
+				t.Errorf("pduValueAsOids(%v, %q): expected error, got %v", c.pdu, c.typ, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("pduValueAsOids(%v, %q): unexpected error: %v", c.pdu, c.typ, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.result) {
+			t.Errorf("pduValueAsOids(%v, %q): got %v, want %v", c.pdu, c.typ, got, c.result)
+		}
+	}
+}
+
 func TestPduValueAsString(t *testing.T) {
 	cases := []struct {
-		pdu    *gosnmp.SnmpPDU
-		typ    string
-		result string
+		pdu     *gosnmp.SnmpPDU
+		typ     string
+		formats config.Formats
+		result  string
 	}{
 		{
 			pdu:    &gosnmp.SnmpPDU{Value: int(-1)},
@@ -748,6 +932,12 @@ func TestPduValueAsString(t *testing.T) {
 			typ:    "InetAddressIPv4",
 			result: "1.2.3.4",
 		},
+		{
+			// Truncated values fall back to hex.
+			pdu:    &gosnmp.SnmpPDU{Value: []byte{1, 2, 3}},
+			typ:    "InetAddressIPv4",
+			result: "0x010203",
+		},
 		{
 			pdu:    &gosnmp.SnmpPDU{Value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}},
 			typ:    "InetAddressIPv6",
@@ -775,9 +965,33 @@ func TestPduValueAsString(t *testing.T) {
 			typ:    "DisplayString",
 			result: "sane�",
 		},
+		{
+			pdu:     &gosnmp.SnmpPDU{Value: []byte{0, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}},
+			typ:     "PhysAddress48",
+			formats: config.Formats{MACFormat: "dot"},
+			result:  "001a.2b3c.4d5e",
+		},
+		{
+			pdu:     &gosnmp.SnmpPDU{Value: []byte{0, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}},
+			typ:     "PhysAddress48",
+			formats: config.Formats{MACFormat: "hyphen_lower"},
+			result:  "00-1a-2b-3c-4d-5e",
+		},
+		{
+			pdu:     &gosnmp.SnmpPDU{Value: []byte{0, 0x1a, 0x2b}},
+			typ:     "OctetString",
+			formats: config.Formats{HexFormat: "colon_lower"},
+			result:  "00:1a:2b",
+		},
+		{
+			pdu:     &gosnmp.SnmpPDU{Value: []byte{0, 0x1a, 0x2b}},
+			typ:     "OctetString",
+			formats: config.Formats{HexFormat: "plain"},
+			result:  "001A2B",
+		},
 	}
 	for _, c := range cases {
-		got := pduValueAsString(c.pdu, c.typ, Metrics{})
+		got := pduValueAsString(c.pdu, c.typ, c.formats, Metrics{})
 		if !reflect.DeepEqual(got, c.result) {
 			t.Errorf("pduValueAsString(%v, %q): got %q, want %q", c.pdu, c.typ, got, c.result)
 		}
@@ -902,10 +1116,10 @@ func TestIndexesToLabels(t *testing.T) {
 			result:   map[string]string{"l": ""},
 		},
 		{
+			// Missing indexes are an error.
 			oid:      []int{},
 			metric:   config.Metric{Indexes: []*config.Index{{Labelname: "l", Type: "gauge"}}},
 			oidToPdu: map[string]gosnmp.SnmpPDU{},
-			result:   map[string]string{"l": "0"},
 		},
 		{
 			oid:      []int{1, 255, 0, 0, 0, 16},
@@ -1081,9 +1295,188 @@ func TestIndexesToLabels(t *testing.T) {
 			},
 			result: map[string]string{"lldpRemTimeMark": "1", "lldpRemLocalPortNum": "8", "lldpRemIndex": "1", "lldpLocPortId": "04:05:06:07:08:09"},
 		},
+		{
+			oid: []int{4, 10, 0, 0, 1, 1234},
+			metric: config.Metric{
+				Indexes: []*config.Index{
+					{Labelname: "addr", Type: "OctetString", DisplayHint: "1d.1d.1d.1d"},
+					{Labelname: "temp", Type: "gauge", DisplayHint: "d-2"},
+				},
+				Lookups: []*config.Lookup{
+					{Labels: []string{"temp"}, Labelname: "serial", Oid: "1.2", Type: "OctetString", DisplayHint: "2x-"},
+				},
+			},
+			oidToPdu: map[string]gosnmp.SnmpPDU{
+				"1.2.1234": gosnmp.SnmpPDU{Value: []byte{0xde, 0xad, 0xbe, 0xef}},
+			},
+			result: map[string]string{"addr": "10.0.0.1", "temp": "12.34", "serial": "dead-beef"},
+		},
+		{
+			oid: []int{4294967295, 12345, 3, 1, 3, 6, 1, 3, 6, 1, 4, 1, 8072},
+			metric: config.Metric{
+				Indexes: []*config.Index{
+					{Labelname: "unsigned", Type: "Unsigned32"},
+					{Labelname: "ticks", Type: "TimeTicks"},
+					{Labelname: "subtree", Type: "ObjectIdentifier"},
+					{Labelname: "context", Type: "ObjectIdentifier", Implied: true},
+				},
+			},
+			result: map[string]string{"unsigned": "4294967295", "ticks": "12345", "subtree": "1.3.6", "context": "1.3.6.1.4.1.8072"},
+		},
+		{
+			oid: []int{10, 0, 0, 1, 0},
+			metric: config.Metric{
+				Indexes: []*config.Index{
+					{Labelname: "addr", Type: "IpAddress"},
+					{Labelname: "empty", Type: "ObjectIdentifier"},
+				},
+			},
+			result: map[string]string{"addr": "10.0.0.1", "empty": ""},
+		},
+		{
+			// Unknown types use the rest of the index rather than panicking.
+			oid: []int{1, 2, 3},
+			metric: config.Metric{
+				Indexes: []*config.Index{
+					{Labelname: "a", Type: "gauge"},
+					{Labelname: "b", Type: "NoSuchType"},
+				},
+			},
+			result: map[string]string{"a": "1", "b": "2.3"},
+		},
+		{
+			oid: []int{0, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e, 2, 0xbe, 0xef},
+			metric: config.Metric{
+				Indexes: []*config.Index{
+					{Labelname: "mac", Type: "PhysAddress48", Formats: config.Formats{MACFormat: "dot"}},
+					{Labelname: "key", Type: "OctetString", Formats: config.Formats{HexFormat: "plain_lower"}},
+				},
+				Lookups: []*config.Lookup{
+					{Labels: []string{"mac"}, Labelname: "peer", Oid: "1.2", Type: "PhysAddress48", Formats: config.Formats{MACFormat: "hyphen_lower"}},
+				},
+			},
+			oidToPdu: map[string]gosnmp.SnmpPDU{
+				"1.2.0.26.43.60.77.94": gosnmp.SnmpPDU{Value: []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}},
+			},
+			result: map[string]string{"mac": "001a.2b3c.4d5e", "key": "beef", "peer": "aa-bb-cc-dd-ee-ff"},
+		},
+		{
+			// Chained lookup, as generated.
+			oid: []int{1, 2},
+			metric: config.Metric{
+				Indexes: []*config.Index{
+					{Labelname: "a", Type: "gauge"},
+					{Labelname: "b", Type: "gauge"},
+					{Labelname: "c", Type: "gauge"},
+				},
+				Lookups: []*config.Lookup{
+					{Labels: []string{"a", "b"}, Labelname: "c", Oid: "1.1", Type: "gauge"},
+					{Labels: []string{"c"}, Labelname: "desc", Oid: "1.2", Type: "DisplayString"},
+				},
+			},
+			oidToPdu: map[string]gosnmp.SnmpPDU{
+				"1.1.1.2": gosnmp.SnmpPDU{Value: 7},
+				"1.2.7":   gosnmp.SnmpPDU{Value: []byte("seven")},
+			},
+			result: map[string]string{"a": "1", "b": "2", "c": "7", "desc": "seven"},
+		},
+		{
+			// dot1dBasePort via dot1dBasePortIfIndex to ifName.
+			oid: []int{3},
+			metric: config.Metric{
+				Indexes: []*config.Index{{Labelname: "dot1dBasePort", Type: "gauge"}},
+				Lookups: []*config.Lookup{
+					{
+						Labels: []string{"dot1dBasePort"}, Labelname: "ifName", Oid: "1.3.6.1.2.1.31.1.1.1.1", Type: "DisplayString",
+						Via: &config.LookupVia{Oid: "1.3.6.1.2.1.17.1.4.1.2", Type: "gauge"},
+					},
+				},
+			},
+			oidToPdu: map[string]gosnmp.SnmpPDU{
+				"1.3.6.1.2.1.17.1.4.1.2.3":     gosnmp.SnmpPDU{Value: 10103},
+				"1.3.6.1.2.1.31.1.1.1.1.3":     gosnmp.SnmpPDU{Value: []byte("wrong")},
+				"1.3.6.1.2.1.31.1.1.1.1.10103": gosnmp.SnmpPDU{Value: []byte("Gi1/0/3")},
+			},
+			result: map[string]string{"dot1dBasePort": "3", "ifName": "Gi1/0/3"},
+		},
+		{
+			// A string valued intermediate column, and a missing one.
+			oid: []int{1, 2},
+			metric: config.Metric{
+				Indexes: []*config.Index{{Labelname: "a", Type: "gauge"}, {Labelname: "b", Type: "gauge"}},
+				Lookups: []*config.Lookup{
+					{
+						Labels: []string{"a"}, Labelname: "byName", Oid: "1.3", Type: "gauge",
+						Via: &config.LookupVia{Oid: "1.2", Type: "DisplayString"},
+					},
+					{
+						Labels: []string{"b"}, Labelname: "missing", Oid: "1.3", Type: "gauge",
+						Via: &config.LookupVia{Oid: "1.2", Type: "DisplayString"},
+					},
+				},
+			},
+			oidToPdu: map[string]gosnmp.SnmpPDU{
+				"1.2.1":       gosnmp.SnmpPDU{Value: []byte("ab")},
+				"1.3.2.97.98": gosnmp.SnmpPDU{Value: 42},
+			},
+			result: map[string]string{"a": "1", "b": "2", "byName": "42", "missing": ""},
+		},
+		{
+			// String values keep their index encoding when chained.
+			oid: []int{1},
+			metric: config.Metric{
+				Indexes: []*config.Index{{Labelname: "a", Type: "gauge"}},
+				Lookups: []*config.Lookup{
+					{Labels: []string{"a"}, Labelname: "name", Oid: "1.2", Type: "DisplayString"},
+					{Labels: []string{"name"}, Labelname: "addr", Oid: "1.3", Type: "InetAddressIPv4"},
+					{Labels: []string{"addr"}, Labelname: "desc", Oid: "1.4", Type: "DisplayString"},
+				},
+			},
+			oidToPdu: map[string]gosnmp.SnmpPDU{
+				"1.2.1":        gosnmp.SnmpPDU{Value: []byte("ab")},
+				"1.3.2.97.98":  gosnmp.SnmpPDU{Value: []byte{10, 0, 0, 1}},
+				"1.4.10.0.0.1": gosnmp.SnmpPDU{Value: []byte("router")},
+			},
+			result: map[string]string{"a": "1", "name": "ab", "addr": "10.0.0.1", "desc": "router"},
+		},
+		// Indexes that are too short are errors, rather than padded with zeros.
+		{
+			oid: []int{10, 0, 0},
+			metric: config.Metric{
+				Indexes: []*config.Index{{Labelname: "addr", Type: "InetAddressIPv4"}},
+			},
+		},
+		{
+			oid: []int{5, 104, 101},
+			metric: config.Metric{
+				Indexes: []*config.Index{{Labelname: "name", Type: "DisplayString"}},
+			},
+		},
+		{
+			oid: []int{1},
+			metric: config.Metric{
+				Indexes: []*config.Index{{Labelname: "a", Type: "gauge"}, {Labelname: "b", Type: "gauge"}},
+			},
+		},
+		{
+			oid: []int{1, 4, 192, 168},
+			metric: config.Metric{
+				Indexes: []*config.Index{{Labelname: "addr", Type: "InetAddress"}},
+			},
+		},
 	}
 	for _, c := range cases {
-		got := indexesToLabels(c.oid, &c.metric, c.oidToPdu, Metrics{})
+		got, err := indexesToLabels(c.oid, &c.metric, c.oidToPdu, Metrics{})
+		if c.result == nil {
+			if err == nil {
+				t.Errorf("indexesToLabels(%v, %v, %v): expected error, got %v", c.oid, c.metric, c.oidToPdu, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("indexesToLabels(%v, %v, %v): unexpected error: %v", c.oid, c.metric, c.oidToPdu, err)
+			continue
+		}
 		if !reflect.DeepEqual(got, c.result) {
 			t.Errorf("indexesToLabels(%v, %v, %v): got %v, want %v", c.oid, c.metric, c.oidToPdu, got, c.result)
 		}
@@ -1401,3 +1794,38 @@ func TestAddAllowedIndices(t *testing.T) {
 		}
 	}
 }
+
+func TestModuleSampleLabels(t *testing.T) {
+	module := &config.Module{
+		StaticLabels: map[string]string{"vendor": "cisco"},
+		ScalarLabels: []*config.ScalarLabel{
+			{Labelname: "sysName", Oid: "1.3.6.1.2.1.1.5.0", Type: "DisplayString"},
+			{Labelname: "sysLocation", Oid: "1.3.6.1.2.1.1.6.0", Type: "DisplayString"},
+		},
+	}
+	oidToPdu := map[string]gosnmp.SnmpPDU{
+		"1.3.6.1.2.1.1.5.0": {Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: []byte("router1")},
+	}
+	got := moduleSampleLabels(module, oidToPdu, Metrics{})
+	// Scalars that weren't returned are left out.
+	expected := map[string]string{"vendor": "cisco", "sysName": "router1"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("moduleSampleLabels: got %v, want %v", got, expected)
+	}
+
+	cases := []struct {
+		oid    string
+		result bool
+	}{
+		{oid: "1.3.6.1.2.1.1.5.0", result: true},
+		{oid: "1.3.6.1.2.1.2.2.1.2.1", result: true},
+		{oid: "1.3.6.1.2.1.22.1", result: false},
+		{oid: "1.3.6.1.2.1.1.6.0", result: false},
+	}
+	for _, c := range cases {
+		got := oidCovered(c.oid, []string{"1.3.6.1.2.1.1.5.0"}, []string{"1.3.6.1.2.1.2"})
+		if got != c.result {
+			t.Errorf("oidCovered(%s): got %v, want %v", c.oid, got, c.result)
+		}
+	}
+}
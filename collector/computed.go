@@ -0,0 +1,121 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+// The values of the operands of computed metrics, by row.
+type computedRows struct {
+	operands map[string]*config.Metric
+	// Values by index OID, then metric name.
+	values  map[string]map[string]float64
+	indexes map[string][]int
+}
+
+func newComputedRows(module *config.Module) *computedRows {
+	rows := &computedRows{
+		operands: map[string]*config.Metric{},
+		values:   map[string]map[string]float64{},
+		indexes:  map[string][]int{},
+	}
+	metrics := make(map[string]*config.Metric, len(module.Metrics))
+	for _, metric := range module.Metrics {
+		metrics[metric.Name] = metric
+	}
+	for _, cm := range module.ComputedMetrics {
+		for _, name := range cm.Expr.Vars() {
+			rows.operands[name] = metrics[name]
+		}
+	}
+	return rows
+}
+
+// Record the value of a metric, if it is used by a computed metric.
+func (r *computedRows) add(indexOids []int, pdu *gosnmp.SnmpPDU, metric *config.Metric, oidToPdu map[string]gosnmp.SnmpPDU) {
+	if _, ok := r.operands[metric.Name]; !ok {
+		return
+	}
+	value, ok := metricPduValue(indexOids, pdu, metric, oidToPdu)
+	if !ok {
+		return
+	}
+	key := listToOid(indexOids)
+	if _, ok := r.values[key]; !ok {
+		r.values[key] = map[string]float64{}
+		r.indexes[key] = indexOids
+	}
+	if metric.Scale != 0.0 {
+		value *= metric.Scale
+	}
+	r.values[key][metric.Name] = value + metric.Offset
+}
+
+// Evaluate the computed metrics for every row that has all their operands.
+// Rows where the result isn't a finite number are skipped.
+func (r *computedRows) samples(module *config.Module, oidToPdu map[string]gosnmp.SnmpPDU, moduleLabels map[string]string, relabelConfigs []*config.RelabelConfig, logger log.Logger, metrics Metrics) []prometheus.Metric {
+	results := []prometheus.Metric{}
+	for _, cm := range module.ComputedMetrics {
+		vars := cm.Expr.Vars()
+		t := prometheus.GaugeValue
+		if cm.Type == "counter" {
+			t = prometheus.CounterValue
+		}
+	RowLoop:
+		for key, values := range r.values {
+			for _, name := range vars {
+				if _, ok := values[name]; !ok {
+					continue RowLoop
+				}
+			}
+			value := cm.Expr.Eval(values)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				level.Debug(logger).Log("msg", "Skipping computed metric with non-finite value", "metric", cm.Name, "index", key, "value", value)
+				continue
+			}
+			// The operands share their indexes, so any of them gives the labels.
+			labels, err := indexesToLabels(r.indexes[key], r.operands[vars[0]], oidToPdu, metrics)
+			if err != nil {
+				continue
+			}
+			for k, v := range moduleLabels {
+				labels[k] = v
+			}
+			labelnames := make([]string, 0, len(labels))
+			labelvalues := make([]string, 0, len(labels))
+			for k, v := range labels {
+				labelnames = append(labelnames, k)
+				labelvalues = append(labelvalues, v)
+			}
+			sample, err := newConstMetric(cm.Name, cm.Help, labelnames, labelvalues, t, value, relabelConfigs)
+			if err != nil {
+				sample = prometheus.NewInvalidMetric(prometheus.NewDesc("snmp_error", "Error calling NewConstMetric", nil, nil),
+					fmt.Errorf("error for computed metric %s with labels %v: %v", cm.Name, labelvalues, err))
+			}
+			if sample != nil {
+				results = append(results, sample)
+			}
+		}
+	}
+	return results
+}
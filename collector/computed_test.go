@@ -0,0 +1,113 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/gosnmp/gosnmp"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+func TestExprEval(t *testing.T) {
+	vars := map[string]float64{"total": 100, "free": 25, "high": 1, "low": 5}
+	cases := []struct {
+		expr   string
+		vars   []string
+		result float64
+		err    bool
+	}{
+		{expr: "total - free", vars: []string{"total", "free"}, result: 75},
+		{expr: "(total - free) / total * 100", vars: []string{"total", "free"}, result: 75},
+		{expr: "high * 2^32 + low", vars: []string{"high", "low"}, result: 4294967301},
+		{expr: "2 ^ 3 ^ 2", result: 512},
+		{expr: "-2^2", result: -4},
+		{expr: "1 + 2 * 3 - -1", result: 8},
+		{expr: "total % 30", vars: []string{"total"}, result: 10},
+		{expr: "free + free * 0.5", vars: []string{"free"}, result: 37.5},
+		{expr: "total -", err: true},
+		{expr: "(total", err: true},
+		{expr: "total free", err: true},
+		{expr: "1..2", err: true},
+		{expr: "total $ 2", err: true},
+		{expr: "", err: true},
+	}
+	for _, c := range cases {
+		expr, err := config.ParseExpr(c.expr)
+		if c.err {
+			if err == nil {
+				t.Errorf("ParseExpr(%q): expected error", c.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseExpr(%q): unexpected error %s", c.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(expr.Vars(), c.vars) {
+			t.Errorf("ParseExpr(%q): got vars %v, want %v", c.expr, expr.Vars(), c.vars)
+		}
+		if got := expr.Eval(vars); got != c.result {
+			t.Errorf("ParseExpr(%q): got %v, want %v", c.expr, got, c.result)
+		}
+	}
+}
+
+func TestComputedSamples(t *testing.T) {
+	index := []*config.Index{{Labelname: "hrStorageIndex", Type: "gauge"}}
+	size := &config.Metric{Name: "hrStorageSize", Oid: "1.5", Type: "gauge", Indexes: index, Scale: 4096}
+	used := &config.Metric{Name: "hrStorageUsed", Oid: "1.6", Type: "gauge", Indexes: index, Scale: 4096}
+	expr, _ := config.ParseExpr("hrStorageSize - hrStorageUsed")
+	ratio, _ := config.ParseExpr("hrStorageUsed / hrStorageSize")
+	module := &config.Module{
+		Metrics: []*config.Metric{size, used},
+		ComputedMetrics: []*config.ComputedMetric{
+			{Name: "hrStorageFree", Help: "Free bytes", Type: "gauge", Expr: *expr},
+			{Name: "hrStorageUsedRatio", Help: "Used ratio", Type: "gauge", Expr: *ratio},
+		},
+	}
+
+	rows := newComputedRows(module)
+	// Row 1 has both operands, row 2 is missing one and row 3 has a zero size.
+	rows.add([]int{1}, &gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 10}, size, nil)
+	rows.add([]int{1}, &gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 4}, used, nil)
+	rows.add([]int{2}, &gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 10}, size, nil)
+	rows.add([]int{3}, &gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 0}, size, nil)
+	rows.add([]int{3}, &gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 0}, used, nil)
+
+	expected := map[string]struct{}{
+		`Desc{fqName: "hrStorageFree", help: "Free bytes", constLabels: {}, variableLabels: {hrStorageIndex}} label:{name:"hrStorageIndex" value:"1"} gauge:{value:24576}`:    {},
+		`Desc{fqName: "hrStorageFree", help: "Free bytes", constLabels: {}, variableLabels: {hrStorageIndex}} label:{name:"hrStorageIndex" value:"3"} gauge:{value:0}`:        {},
+		`Desc{fqName: "hrStorageUsedRatio", help: "Used ratio", constLabels: {}, variableLabels: {hrStorageIndex}} label:{name:"hrStorageIndex" value:"1"} gauge:{value:0.4}`: {},
+	}
+	for _, sample := range rows.samples(module, nil, nil, nil, log.NewNopLogger(), Metrics{}) {
+		m := &dto.Metric{}
+		if err := sample.Write(m); err != nil {
+			t.Fatalf("Error writing metric: %v", err)
+		}
+		got := strings.ReplaceAll(sample.Desc().String()+" "+m.String(), "  ", " ")
+		if _, ok := expected[got]; !ok {
+			t.Errorf("Got metric:      %v", got)
+		}
+		delete(expected, got)
+	}
+	for e := range expected {
+		t.Errorf("Expected metric: %v, but was not returned.", e)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sync"
+	"time"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+var (
+	counterStateTTL = kingpin.Flag("snmp.counter-state-ttl", "How long to keep the state of extended counters of targets and series that are no longer scraped.").Default("1h").Duration()
+
+	// State of extended counters, shared by all scrapes.
+	counterStates = newCounterStore()
+)
+
+// The last value of a Counter32 series, and the 64-bit value it was extended to.
+type counterState struct {
+	raw    uint32
+	value  uint64
+	uptime time.Duration
+	seen   time.Time
+}
+
+// Extended counters of one module of a target.
+type targetCounters struct {
+	mtx    sync.Mutex
+	seen   time.Time
+	series map[string]*counterState
+}
+
+type counterKey struct {
+	auth, module, target string
+}
+
+type counterStore struct {
+	mtx     sync.Mutex
+	swept   time.Time
+	targets map[counterKey]*targetCounters
+}
+
+func newCounterStore() *counterStore {
+	return &counterStore{targets: map[counterKey]*targetCounters{}}
+}
+
+// Get the counters of a target, expiring its series not scraped within the
+// TTL. Targets not scraped within the TTL are swept at most once per TTL.
+func (s *counterStore) forTarget(key counterKey, now time.Time) *targetCounters {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if now.Sub(s.swept) > *counterStateTTL {
+		s.sweep(now)
+	}
+	t, ok := s.targets[key]
+	if !ok {
+		t = &targetCounters{series: map[string]*counterState{}}
+		s.targets[key] = t
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.seen = now
+	for oid, state := range t.series {
+		if now.Sub(state.seen) > *counterStateTTL {
+			delete(t.series, oid)
+		}
+	}
+	return t
+}
+
+// Delete the counters of targets not scraped within the TTL.
+func (s *counterStore) sweep(now time.Time) {
+	for k, t := range s.targets {
+		t.mtx.Lock()
+		expired := now.Sub(t.seen) > *counterStateTTL
+		t.mtx.Unlock()
+		if expired {
+			delete(s.targets, k)
+		}
+	}
+	s.swept = now
+}
+
+// sysUpTime.0, the time since the agent was started.
+const sysUpTimeOid = "1.3.6.1.2.1.1.3.0"
+
+// The oid of the uptime of the agents of a module, the one counters are
+// extended with if any, otherwise sysUpTime.0.
+func moduleUptimeOid(module *config.Module) string {
+	if module.ExtendCounters != nil {
+		return module.ExtendCounters.UptimeOid
+	}
+	return sysUpTimeOid
+}
+
+// The uptime of an agent from a TimeTicks PDU, or an integer in seconds.
+func pduUptime(pdu gosnmp.SnmpPDU) time.Duration {
+	v := gosnmp.ToBigInt(pdu.Value).Int64()
+	if pdu.Type == gosnmp.TimeTicks {
+		return time.Duration(v) * 10 * time.Millisecond
+	}
+	return time.Duration(v) * time.Second
+}
+
+// The uptime of an agent as TimeTicks, wrapping as sysUpTime does, from a
+// TimeTicks PDU or an integer in seconds.
+func pduUptimeTicks(pdu gosnmp.SnmpPDU) uint32 {
+	return uptimeTicks(pduUptime(pdu))
+}
+
+// An uptime as TimeTicks, wrapping as sysUpTime does.
+func uptimeTicks(uptime time.Duration) uint32 {
+	return uint32(uptime / (10 * time.Millisecond))
+}
+
+// Events up to this many ticks after the uptime was read, during the
+// scrape, are taken as happening when it was read rather than a wrap ago.
+const uptimeSlack = 3600 * 100
+
+// The Unix time of an event from the sysUpTime it happened at, such as
+// ifLastChange, given the current sysUpTime. Unsigned arithmetic handles
+// the uptime wrapping, every 497 days.
+func timeTicksAsTimestamp(ticks, uptime uint32, now time.Time) float64 {
+	since := uptime - ticks
+	if ticks-uptime < uptimeSlack {
+		since = 0
+	}
+	return float64(now.Add(-time.Duration(since)*10*time.Millisecond).UnixNano()) / 1e9
+}
+
+// Whether the agent has restarted since the previous value, which is the
+// case when its uptime went backwards or is shorter than the time since.
+// The uptime going backwards but, with unsigned arithmetic, advancing by
+// about the time since, is sysUpTime wrapping every 497 days rather than a
+// restart.
+func (s *counterState) restarted(uptime time.Duration, now time.Time) bool {
+	since := now.Sub(s.seen)
+	if uptime < s.uptime {
+		advance := time.Duration(uptimeTicks(uptime)-uptimeTicks(s.uptime)) * 10 * time.Millisecond
+		slack := time.Duration(uptimeSlack) * 10 * time.Millisecond
+		return advance < since-slack || advance > since+slack
+	}
+	return uptime < since
+}
+
+// Extend a Counter32 value of the series with the given oid to 64 bits.
+// A lower value than the previous one is a wrap, unless the agent has
+// restarted since.
+func (t *targetCounters) extend(oid string, raw uint32, uptime time.Duration, now time.Time) uint64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	state, ok := t.series[oid]
+	switch {
+	case !ok:
+		state = &counterState{value: uint64(raw)}
+		t.series[oid] = state
+	case state.restarted(uptime, now):
+		// Restarted, the counter starts over.
+		state.value = uint64(raw)
+	default:
+		// Unsigned arithmetic handles the wrap.
+		state.value += uint64(raw - state.raw)
+	}
+	state.raw = raw
+	state.uptime = uptime
+	state.seen = now
+	return state.value
+}
+
+// Replace a Counter32 PDU by a Counter64 one with its extended value.
+func (t *targetCounters) extendPdu(oid string, pdu gosnmp.SnmpPDU, uptime time.Duration, now time.Time) gosnmp.SnmpPDU {
+	raw := uint32(gosnmp.ToBigInt(pdu.Value).Uint64())
+	pdu.Type = gosnmp.Counter64
+	pdu.Value = t.extend(oid, raw, uptime, now)
+	return pdu
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestExtendCounter(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	type scrape struct {
+		after  time.Duration // Since the start.
+		uptime time.Duration
+		raw    uint32
+		value  uint64
+	}
+	cases := []struct {
+		name    string
+		scrapes []scrape
+	}{
+		{
+			name: "increasing",
+			scrapes: []scrape{
+				{after: 0, uptime: time.Hour, raw: 100, value: 100},
+				{after: time.Minute, uptime: time.Hour + time.Minute, raw: 300, value: 300},
+			},
+		},
+		{
+			name: "wrap",
+			scrapes: []scrape{
+				{after: 0, uptime: time.Hour, raw: 4294967200, value: 4294967200},
+				{after: time.Minute, uptime: time.Hour + time.Minute, raw: 100, value: 4294967396},
+				{after: 2 * time.Minute, uptime: time.Hour + 2*time.Minute, raw: 4294967000, value: 8589934296},
+				{after: 3 * time.Minute, uptime: time.Hour + 3*time.Minute, raw: 10, value: 8589934602},
+			},
+		},
+		{
+			name: "restart with lower uptime",
+			scrapes: []scrape{
+				{after: 0, uptime: time.Hour, raw: 4294967200, value: 4294967200},
+				{after: time.Minute, uptime: 30 * time.Second, raw: 100, value: 100},
+				{after: 2 * time.Minute, uptime: 90 * time.Second, raw: 200, value: 200},
+			},
+		},
+		{
+			name: "restart with higher uptime shorter than the time since the last scrape",
+			scrapes: []scrape{
+				{after: 0, uptime: time.Minute, raw: 4294967200, value: 4294967200},
+				{after: time.Hour, uptime: 30 * time.Minute, raw: 100, value: 100},
+			},
+		},
+		{
+			name: "uptime wrap",
+			scrapes: []scrape{
+				{after: 0, uptime: 4294967000 * 10 * time.Millisecond, raw: 4294967200, value: 4294967200},
+				{after: time.Minute, uptime: 5704 * 10 * time.Millisecond, raw: 100, value: 4294967396},
+			},
+		},
+		{
+			name: "restart with lower uptime near the wrap",
+			scrapes: []scrape{
+				{after: 0, uptime: 4294967000 * 10 * time.Millisecond, raw: 4294967200, value: 4294967200},
+				{after: 3 * time.Hour, uptime: time.Hour, raw: 100, value: 100},
+			},
+		},
+	}
+	for _, c := range cases {
+		counters := newCounterStore().forTarget(counterKey{target: "a"}, start)
+		for i, s := range c.scrapes {
+			got := counters.extend("1.1.1", s.raw, s.uptime, start.Add(s.after))
+			if got != s.value {
+				t.Errorf("%s, scrape %d: got %d, want %d", c.name, i, got, s.value)
+			}
+		}
+	}
+}
+
+func TestCounterStoreExpiry(t *testing.T) {
+	_, err := kingpin.CommandLine.Parse([]string{"--snmp.counter-state-ttl=10m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kingpin.CommandLine.Parse([]string{})
+	start := time.Unix(1700000000, 0)
+	s := newCounterStore()
+
+	a := s.forTarget(counterKey{target: "a"}, start)
+	a.extend("1.1.1", 10, time.Hour, start)
+	a.extend("1.1.2", 10, time.Hour, start)
+	s.forTarget(counterKey{target: "b"}, start)
+
+	// Target a is scraped again, but only one of its series.
+	a = s.forTarget(counterKey{target: "a"}, start.Add(5*time.Minute))
+	a.extend("1.1.1", 20, time.Hour+5*time.Minute, start.Add(5*time.Minute))
+	s.forTarget(counterKey{target: "b"}, start.Add(9*time.Minute))
+	// Other targets are only swept once per TTL.
+	s.forTarget(counterKey{target: "c"}, start.Add(15*time.Minute))
+	if _, ok := s.targets[counterKey{target: "b"}]; !ok {
+		t.Errorf("Target was expired before the next sweep")
+	}
+	a = s.forTarget(counterKey{target: "a"}, start.Add(12*time.Minute))
+	s.forTarget(counterKey{target: "c"}, start.Add(26*time.Minute))
+	if _, ok := s.targets[counterKey{target: "b"}]; ok {
+		t.Errorf("Target not scraped within the TTL was kept")
+	}
+	if _, ok := a.series["1.1.2"]; ok {
+		t.Errorf("Series not seen within the TTL was kept")
+	}
+	if _, ok := a.series["1.1.1"]; !ok {
+		t.Errorf("Series seen within the TTL was expired")
+	}
+
+	pdu := a.extendPdu("1.1.1", gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint(30)}, time.Hour+12*time.Minute, start.Add(12*time.Minute))
+	if pdu.Type != gosnmp.Counter64 || pdu.Value != uint64(30) {
+		t.Errorf("Got extended PDU %v", pdu)
+	}
+}
+
+func TestPduUptimeTicks(t *testing.T) {
+	cases := []struct {
+		pdu      gosnmp.SnmpPDU
+		expected uint32
+	}{
+		{pdu: gosnmp.SnmpPDU{Type: gosnmp.TimeTicks, Value: uint32(123456)}, expected: 123456},
+		// snmpEngineTime, in seconds.
+		{pdu: gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 1234}, expected: 123400},
+		// Wraps as sysUpTime would.
+		{pdu: gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 42949673}, expected: 4},
+	}
+	for _, c := range cases {
+		if got := pduUptimeTicks(c.pdu); got != c.expected {
+			t.Errorf("pduUptimeTicks(%v): got %d, want %d", c.pdu, got, c.expected)
+		}
+	}
+}
+
+func TestTimeTicksAsTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cases := []struct {
+		ticks, uptime uint32
+		expected      float64
+	}{
+		{ticks: 1000, uptime: 3000, expected: 1699999980},
+		{ticks: 0, uptime: 100000, expected: 1699999000},
+		// The uptime wrapped since the change.
+		{ticks: 4294967196, uptime: 100, expected: 1699999998},
+		// Changed after the uptime was read.
+		{ticks: 3050, uptime: 3000, expected: 1700000000},
+	}
+	for _, c := range cases {
+		if got := timeTicksAsTimestamp(c.ticks, c.uptime, now); got != c.expected {
+			t.Errorf("timeTicksAsTimestamp(%d, %d): got %v, want %v", c.ticks, c.uptime, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+// One part of an octet string DISPLAY-HINT, see RFC 2579 section 3.1.
+type octetHintSpec struct {
+	repeat     bool
+	length     int
+	format     byte
+	separator  byte
+	terminator byte
+}
+
+func parseOctetStringHint(hint string) ([]octetHintSpec, error) {
+	specs := []octetHintSpec{}
+	for i := 0; i < len(hint); {
+		spec := octetHintSpec{}
+		if hint[i] == '*' {
+			spec.repeat = true
+			i++
+		}
+		start := i
+		for i < len(hint) && hint[i] >= '0' && hint[i] <= '9' {
+			i++
+		}
+		if start == i {
+			return nil, fmt.Errorf("missing octet length in display hint %q", hint)
+		}
+		spec.length, _ = strconv.Atoi(hint[start:i])
+		if spec.length == 0 {
+			return nil, fmt.Errorf("zero octet length in display hint %q", hint)
+		}
+		if i == len(hint) {
+			return nil, fmt.Errorf("missing display format in display hint %q", hint)
+		}
+		switch hint[i] {
+		case 'x', 'd', 'o', 'a', 't':
+			spec.format = hint[i]
+		default:
+			return nil, fmt.Errorf("unknown display format %q in display hint %q", hint[i], hint)
+		}
+		i++
+		if i < len(hint) && !isHintSpecStart(hint[i]) {
+			spec.separator = hint[i]
+			i++
+			if spec.repeat && i < len(hint) && !isHintSpecStart(hint[i]) {
+				spec.terminator = hint[i]
+				i++
+			}
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("empty display hint")
+	}
+	return specs, nil
+}
+
+func isHintSpecStart(c byte) bool {
+	return c == '*' || (c >= '0' && c <= '9')
+}
+
+// Render an octet string using a DISPLAY-HINT such as "1d.1d.1d.1d" or "2x:".
+// The last part of the hint is repeated until the value is used up.
+func octetStringDisplayHint(hint string, value []byte) (string, error) {
+	specs, err := parseOctetStringHint(hint)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i := 0; len(value) > 0; i++ {
+		spec := specs[len(specs)-1]
+		if i < len(specs) {
+			spec = specs[i]
+		}
+		repeat := 1
+		if spec.repeat {
+			repeat = int(value[0])
+			value = value[1:]
+		}
+		for r := 0; r < repeat && len(value) > 0; r++ {
+			n := spec.length
+			if n > len(value) {
+				n = len(value)
+			}
+			part := value[:n]
+			value = value[n:]
+			switch spec.format {
+			case 'a', 't':
+				// Invalid UTF-8 is replaced by the caller.
+				b.Write(part)
+			case 'x':
+				for _, o := range part {
+					fmt.Fprintf(&b, "%02x", o)
+				}
+			case 'd':
+				// Fields can be longer than 8 octets.
+				b.WriteString(new(big.Int).SetBytes(part).Text(10))
+			case 'o':
+				b.WriteString(new(big.Int).SetBytes(part).Text(8))
+			}
+			if len(value) == 0 {
+				break
+			}
+			if spec.terminator != 0 && r == repeat-1 {
+				b.WriteByte(spec.terminator)
+			} else if spec.separator != 0 {
+				b.WriteByte(spec.separator)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// Render an integer using a DISPLAY-HINT such as "d-2" or "x".
+func integerDisplayHint(hint string, value int64) (string, error) {
+	if hint == "" {
+		return "", fmt.Errorf("empty display hint")
+	}
+	switch hint[0] {
+	case 'x':
+		if value < 0 {
+			return "-" + strconv.FormatUint(uint64(-value), 16), nil
+		}
+		return strconv.FormatUint(uint64(value), 16), nil
+	case 'o':
+		return strconv.FormatInt(value, 8), nil
+	case 'b':
+		return strconv.FormatInt(value, 2), nil
+	case 'd':
+		if len(hint) == 1 {
+			return strconv.FormatInt(value, 10), nil
+		}
+		if hint[1] != '-' {
+			return "", fmt.Errorf("invalid display hint %q", hint)
+		}
+		places, err := strconv.Atoi(hint[2:])
+		if err != nil || places < 0 {
+			return "", fmt.Errorf("invalid display hint %q", hint)
+		}
+		sign := ""
+		if value < 0 {
+			sign = "-"
+			value = -value
+		}
+		digits := strconv.FormatInt(value, 10)
+		if places == 0 {
+			return sign + digits, nil
+		}
+		if len(digits) <= places {
+			digits = strings.Repeat("0", places-len(digits)+1) + digits
+		}
+		return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:], nil
+	default:
+		return "", fmt.Errorf("invalid display hint %q", hint)
+	}
+}
+
+// Render a PDU value as a label value, using the DISPLAY-HINT if there is one
+// and decoding strings from their character set.
+func pduValueAsLabel(pdu *gosnmp.SnmpPDU, typ, displayHint, encoding string, formats config.Formats, metrics Metrics) string {
+	if displayHint != "" {
+		var str string
+		var err error
+		switch v := pdu.Value.(type) {
+		case []byte:
+			str, err = octetStringDisplayHint(displayHint, v)
+		case int, int64, uint, uint32, uint64:
+			str, err = integerDisplayHint(displayHint, gosnmp.ToBigInt(v).Int64())
+		default:
+			err = fmt.Errorf("display hints not supported for %T", v)
+		}
+		if err == nil {
+			return strings.ToValidUTF8(str, "�")
+		}
+	}
+	if v, ok := pdu.Value.([]byte); ok && encoding != "" && typ == "DisplayString" {
+		return strings.ToValidUTF8(decodeString(encoding, v), "�")
+	}
+	return pduValueAsString(pdu, typ, formats, metrics)
+}
+
+// Render an index using its DISPLAY-HINT, given the oids used by it.
+func indexDisplayHint(index *config.Index, subOid []int) (string, error) {
+	switch index.Type {
+	case "OctetString":
+		// Variable length indexes start with the length.
+		if index.FixedSize == 0 && !index.Implied && len(subOid) > 0 {
+			subOid = subOid[1:]
+		}
+		value := make([]byte, len(subOid))
+		for i, o := range subOid {
+			value[i] = byte(o)
+		}
+		str, err := octetStringDisplayHint(index.DisplayHint, value)
+		return strings.ToValidUTF8(str, "�"), err
+	case "Integer32", "Integer", "gauge", "counter", "Unsigned32", "TimeTicks":
+		if len(subOid) == 0 {
+			return "", fmt.Errorf("missing index")
+		}
+		return integerDisplayHint(index.DisplayHint, int64(subOid[0]))
+	default:
+		return "", fmt.Errorf("display hints not supported for index type %s", index.Type)
+	}
+}
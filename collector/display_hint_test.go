@@ -0,0 +1,128 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+func TestOctetStringDisplayHint(t *testing.T) {
+	cases := []struct {
+		hint   string
+		value  []byte
+		result string
+		err    bool
+	}{
+		{hint: "1d.1d.1d.1d", value: []byte{192, 168, 0, 1}, result: "192.168.0.1"},
+		{hint: "1x:", value: []byte{0, 1, 2, 3, 4, 0xff}, result: "00:01:02:03:04:ff"},
+		{hint: "2x:", value: []byte{0x12, 0x34, 0x56, 0x78, 0x9a}, result: "1234:5678:9a"},
+		{hint: "255a", value: []byte("hello"), result: "hello"},
+		{hint: "255t", value: []byte("grüße"), result: "grüße"},
+		{hint: "1o", value: []byte{8, 9}, result: "1011"},
+		{hint: "4d", value: []byte{0, 1, 0, 0}, result: "65536"},
+		// Fields longer than 8 octets.
+		{hint: "10x", value: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0xab}, result: "010203040506070809ab"},
+		{hint: "9d", value: []byte{1, 0, 0, 0, 0, 0, 0, 0, 0}, result: "18446744073709551616"},
+		{hint: "9o", value: []byte{1, 0, 0, 0, 0, 0, 0, 0, 0}, result: "2000000000000000000000"},
+		// DateAndTime.
+		{hint: "2d-1d-1d,1d:1d:1d.1d,1a1d:1d", value: []byte{0x07, 0xe8, 1, 2, 3, 4, 5, 6, '+', 2, 0}, result: "2024-1-2,3:4:5.6,+2:0"},
+		// Repeat indicator and terminator.
+		{hint: "*1d./", value: []byte{2, 1, 2, 2, 3, 4}, result: "1.2/3.4"},
+		{hint: "1a", value: []byte{}, result: ""},
+		{hint: "x", err: true},
+		{hint: "1q", err: true},
+		{hint: "0a", err: true},
+		{hint: "", err: true},
+	}
+	for _, c := range cases {
+		got, err := octetStringDisplayHint(c.hint, c.value)
+		if c.err {
+			if err == nil {
+				t.Errorf("octetStringDisplayHint(%q, %v): expected error, got %q", c.hint, c.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("octetStringDisplayHint(%q, %v): unexpected error: %s", c.hint, c.value, err)
+			continue
+		}
+		if got != c.result {
+			t.Errorf("octetStringDisplayHint(%q, %v): got %q, want %q", c.hint, c.value, got, c.result)
+		}
+	}
+}
+
+func TestIntegerDisplayHint(t *testing.T) {
+	cases := []struct {
+		hint   string
+		value  int64
+		result string
+		err    bool
+	}{
+		{hint: "d", value: 1234, result: "1234"},
+		{hint: "d-2", value: 1234, result: "12.34"},
+		{hint: "d-2", value: 5, result: "0.05"},
+		{hint: "d-2", value: -150, result: "-1.50"},
+		{hint: "d-0", value: 7, result: "7"},
+		{hint: "x", value: 255, result: "ff"},
+		{hint: "o", value: 8, result: "10"},
+		{hint: "b", value: 5, result: "101"},
+		{hint: "d-a", err: true},
+		{hint: "q", err: true},
+	}
+	for _, c := range cases {
+		got, err := integerDisplayHint(c.hint, c.value)
+		if c.err {
+			if err == nil {
+				t.Errorf("integerDisplayHint(%q, %d): expected error, got %q", c.hint, c.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("integerDisplayHint(%q, %d): unexpected error: %s", c.hint, c.value, err)
+			continue
+		}
+		if got != c.result {
+			t.Errorf("integerDisplayHint(%q, %d): got %q, want %q", c.hint, c.value, got, c.result)
+		}
+	}
+}
+
+func TestPduValueAsLabel(t *testing.T) {
+	cases := []struct {
+		pdu    *gosnmp.SnmpPDU
+		typ    string
+		hint   string
+		result string
+	}{
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{10, 0, 0, 1}}, typ: "OctetString", hint: "1d.1d.1d.1d", result: "10.0.0.1"},
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{10, 0, 0, 1}}, typ: "OctetString", result: "0x0A000001"},
+		{pdu: &gosnmp.SnmpPDU{Value: 2550}, typ: "gauge", hint: "d-1", result: "255.0"},
+		{pdu: &gosnmp.SnmpPDU{Value: uint(2550)}, typ: "gauge", hint: "d-1", result: "255.0"},
+		// A decoded Opaque I64.
+		{pdu: &gosnmp.SnmpPDU{Type: opaqueInteger64, Value: int64(-1234)}, typ: "gauge", hint: "d-2", result: "-12.34"},
+		// Invalid hints fall back to the type.
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{10, 0, 0, 1}}, typ: "OctetString", hint: "q", result: "0x0A000001"},
+	}
+	for _, c := range cases {
+		got := pduValueAsLabel(c.pdu, c.typ, c.hint, "", config.Formats{}, Metrics{})
+		if got != c.result {
+			t.Errorf("pduValueAsLabel(%v, %q, %q): got %q, want %q", c.pdu, c.typ, c.hint, got, c.result)
+		}
+	}
+}
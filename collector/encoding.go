@@ -0,0 +1,105 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+// Character sets tried by auto-detection when a value isn't valid UTF-8 and
+// doesn't look like Western European text, in order of preference.
+// Windows-1252 is a superset of the printable part of Latin-1.
+var autoEncodings = []encoding.Encoding{
+	simplifiedchinese.GB18030,
+	traditionalchinese.Big5,
+	charmap.Windows1252,
+}
+
+// Convert a string value in the given character set to UTF-8.
+// Values that can't be decoded are returned as is.
+func decodeString(name string, value []byte) string {
+	switch name {
+	case "":
+		return string(value)
+	case config.EncodingAuto:
+		if utf8.Valid(value) {
+			return string(value)
+		}
+		// Latin-1 text such as "Müller" is often valid GB18030 too.
+		if str, ok := decodeWith(charmap.Windows1252, value); ok && isWestern(str) {
+			return str
+		}
+		for _, enc := range autoEncodings {
+			if str, ok := decodeWith(enc, value); ok && isPrintable(str) {
+				return str
+			}
+		}
+		return string(value)
+	default:
+		enc, err := ianaindex.IANA.Encoding(name)
+		if err != nil || enc == nil {
+			return string(value)
+		}
+		if str, ok := decodeWith(enc, value); ok {
+			return str
+		}
+		return string(value)
+	}
+}
+
+func decodeWith(enc encoding.Encoding, value []byte) (string, bool) {
+	decoded, err := enc.NewDecoder().Bytes(value)
+	if err != nil {
+		return "", false
+	}
+	return string(decoded), true
+}
+
+// Whether a value decoded as Windows-1252 looks like Western European text,
+// with only single letters between ASCII characters. Text in double byte
+// character sets gives runs of symbols and letters instead.
+func isWestern(str string) bool {
+	prevHigh := false
+	for _, r := range str {
+		high := r >= utf8.RuneSelf
+		if high && (prevHigh || !unicode.IsLetter(r)) {
+			return false
+		}
+		if !high && !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+		prevHigh = high
+	}
+	return true
+}
+
+// Whether a decoded value looks like text, rather than the result of
+// decoding it with the wrong character set.
+func isPrintable(str string) bool {
+	for _, r := range str {
+		if r == utf8.RuneError || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+func TestDecodeString(t *testing.T) {
+	cases := []struct {
+		encoding string
+		value    []byte
+		result   string
+	}{
+		{encoding: "", value: []byte("plain"), result: "plain"},
+		{encoding: "GBK", value: []byte{177, 177, 190, 169, 194, 183}, result: "北京路"},
+		{encoding: "big5", value: []byte{165, 95, 168, 202, 184, 244}, result: "北京路"},
+		{encoding: "latin1", value: []byte{90, 252, 114, 105, 99, 104}, result: "Zürich"},
+		{encoding: "ISO-8859-1", value: []byte("ascii"), result: "ascii"},
+		// Auto-detection.
+		{encoding: "auto", value: []byte("北京路"), result: "北京路"},
+		{encoding: "auto", value: []byte{177, 177, 190, 169, 194, 183}, result: "北京路"},
+		{encoding: "auto", value: []byte{165, 95, 168, 202, 184, 244}, result: "北京路"},
+		{encoding: "auto", value: []byte{99, 97, 102, 233}, result: "café"},
+		// Also valid GB18030, but Latin-1 text.
+		{encoding: "auto", value: []byte{77, 252, 108, 108, 101, 114}, result: "Müller"},
+		{encoding: "auto", value: []byte{83, 227, 111, 32, 80, 97, 117, 108, 111}, result: "São Paulo"},
+		// Unknown encodings leave the value alone.
+		{encoding: "no-such-charset", value: []byte{233}, result: "\xe9"},
+	}
+	for _, c := range cases {
+		got := decodeString(c.encoding, c.value)
+		if got != c.result {
+			t.Errorf("decodeString(%q, %v): got %q, want %q", c.encoding, c.value, got, c.result)
+		}
+	}
+}
+
+func TestPduValueAsLabelEncoding(t *testing.T) {
+	cases := []struct {
+		pdu      *gosnmp.SnmpPDU
+		typ      string
+		encoding string
+		result   string
+	}{
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{90, 252, 114, 105, 99, 104}}, typ: "DisplayString", encoding: "latin1", result: "Zürich"},
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{90, 252, 114, 105, 99, 104}}, typ: "DisplayString", result: "Z�rich"},
+		// Only DisplayStrings are decoded.
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{90, 252}}, typ: "OctetString", encoding: "latin1", result: "0x5AFC"},
+		{pdu: &gosnmp.SnmpPDU{Value: 3}, typ: "gauge", encoding: "latin1", result: "3"},
+		// Undecodable values are made valid UTF-8.
+		{pdu: &gosnmp.SnmpPDU{Value: []byte{0xb1}}, typ: "DisplayString", encoding: "GBK", result: "�"},
+	}
+	for _, c := range cases {
+		got := pduValueAsLabel(c.pdu, c.typ, "", c.encoding, config.Formats{}, Metrics{})
+		if got != c.result {
+			t.Errorf("pduValueAsLabel(%v, %q, %q): got %q, want %q", c.pdu, c.typ, c.encoding, got, c.result)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// NET-SNMP wraps types SNMPv2 has no room for in an Opaque, as an extension
+// tag followed by the tag of the type, its length and its value. gosnmp
+// decodes the Float and Double ones, leaving the others as the bytes of the
+// Opaque. These are the types of the others once decoded, alongside
+// gosnmp.OpaqueFloat and gosnmp.OpaqueDouble.
+const (
+	opaqueCounter64  gosnmp.Asn1BER = 0x76
+	opaqueInteger64  gosnmp.Asn1BER = 0x7a
+	opaqueUnsigned64 gosnmp.Asn1BER = 0x7b
+
+	opaqueExtensionTag = 0x9f
+)
+
+// The name of an ASN.1 type, including the decoded Opaque ones.
+func asn1TypeName(t gosnmp.Asn1BER) string {
+	switch t {
+	case opaqueCounter64:
+		return "OpaqueCounter64"
+	case opaqueInteger64:
+		return "OpaqueInteger64"
+	case opaqueUnsigned64:
+		return "OpaqueUnsigned64"
+	}
+	return t.String()
+}
+
+// Decode an Opaque PDU holding a NET-SNMP Counter64, I64 or U64, with a
+// uint64 or int64 value. Other PDUs are returned unchanged.
+func decodeOpaque(pdu gosnmp.SnmpPDU) (gosnmp.SnmpPDU, error) {
+	data, ok := pdu.Value.([]byte)
+	if pdu.Type != gosnmp.Opaque || !ok || len(data) < 3 || data[0] != opaqueExtensionTag {
+		return pdu, nil
+	}
+	t := gosnmp.Asn1BER(data[1])
+	switch t {
+	case opaqueCounter64, opaqueInteger64, opaqueUnsigned64:
+	default:
+		return pdu, nil
+	}
+	length := int(data[2])
+	value := data[3:]
+	// Unsigned values can have a leading zero byte.
+	if length != len(value) || length == 0 || length > 9 || (length == 9 && (value[0] != 0 || t == opaqueInteger64)) {
+		return pdu, fmt.Errorf("invalid %s of length %d in %d bytes", asn1TypeName(t), length, len(value))
+	}
+
+	var v uint64
+	for _, b := range value {
+		v = v<<8 | uint64(b)
+	}
+	pdu.Type = t
+	if t == opaqueInteger64 {
+		// Sign extend from the length of the value.
+		shift := 64 - 8*uint(length)
+		pdu.Value = int64(v<<shift) >> shift
+	} else {
+		pdu.Value = v
+	}
+	return pdu, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestDecodeOpaque(t *testing.T) {
+	cases := []struct {
+		name  string
+		pdu   gosnmp.SnmpPDU
+		typ   gosnmp.Asn1BER
+		value interface{}
+		float float64
+		err   bool
+	}{
+		{
+			name:  "counter64",
+			pdu:   gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x9f, 0x76, 0x05, 0x01, 0x00, 0x00, 0x00, 0x00}},
+			typ:   opaqueCounter64,
+			value: uint64(1 << 32),
+			float: 1 << 32,
+		},
+		{
+			name:  "counter64 with leading zero byte",
+			pdu:   gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x9f, 0x76, 0x09, 0x00, 0, 0, 0, 0, 0, 0, 0x01, 0x00}},
+			typ:   opaqueCounter64,
+			value: uint64(256),
+			float: 256,
+		},
+		{
+			name:  "negative i64",
+			pdu:   gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x9f, 0x7a, 0x02, 0xff, 0x38}},
+			typ:   opaqueInteger64,
+			value: int64(-200),
+			float: -200,
+		},
+		{
+			name:  "positive i64",
+			pdu:   gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x9f, 0x7a, 0x02, 0x00, 0xc8}},
+			typ:   opaqueInteger64,
+			value: int64(200),
+			float: 200,
+		},
+		{
+			name:  "u64",
+			pdu:   gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x9f, 0x7b, 0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+			typ:   opaqueUnsigned64,
+			value: uint64(1<<64 - 1),
+			float: 1<<64 - 1,
+		},
+		{
+			name:  "other opaque",
+			pdu:   gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x01, 0x02}},
+			typ:   gosnmp.Opaque,
+			value: []byte{0x01, 0x02},
+		},
+		{
+			name:  "float",
+			pdu:   gosnmp.SnmpPDU{Type: gosnmp.OpaqueFloat, Value: float32(1.5)},
+			typ:   gosnmp.OpaqueFloat,
+			value: float32(1.5),
+			float: 1.5,
+		},
+		{
+			name: "truncated",
+			pdu:  gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x9f, 0x76, 0x08, 0x01}},
+			typ:  gosnmp.Opaque,
+			err:  true,
+		},
+		{
+			name: "i64 too long",
+			pdu:  gosnmp.SnmpPDU{Type: gosnmp.Opaque, Value: []byte{0x9f, 0x7a, 0x09, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+			typ:  gosnmp.Opaque,
+			err:  true,
+		},
+	}
+	for _, c := range cases {
+		pdu, err := decodeOpaque(c.pdu)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected error", c.name)
+			}
+			if pdu.Type != c.typ {
+				t.Errorf("%s: got type %s, want it unchanged", c.name, asn1TypeName(pdu.Type))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.name, err)
+			continue
+		}
+		if pdu.Type != c.typ {
+			t.Errorf("%s: got type %s, want %s", c.name, asn1TypeName(pdu.Type), asn1TypeName(c.typ))
+		}
+		if _, ok := c.value.([]byte); ok {
+			continue
+		}
+		if pdu.Value != c.value {
+			t.Errorf("%s: got value %v (%T), want %v (%T)", c.name, pdu.Value, pdu.Value, c.value, c.value)
+		}
+		if got := getPduValue(&pdu); got != c.float {
+			t.Errorf("%s: got float %v, want %v", c.name, got, c.float)
+		}
+	}
+}
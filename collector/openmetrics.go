@@ -0,0 +1,185 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FamilyMetadata is OpenMetrics metadata of a metric family that the
+// client library can't represent.
+type FamilyMetadata struct {
+	// stateset or info, empty to use the type of the family.
+	Type string
+	Unit string
+}
+
+// OpenMetricsMetadata returns the metadata of the metric families of the
+// modules, by name of the family as gathered. The names are those left by
+// the metric relabel configs of the modules. As those are applied to the
+// name alone, the configured name is kept too for renames depending on
+// other labels.
+func OpenMetricsMetadata(modules []*NamedModule) map[string]FamilyMetadata {
+	metadata := map[string]FamilyMetadata{}
+	for _, module := range modules {
+		add := func(name string, md FamilyMetadata) {
+			metadata[name] = md
+			if len(module.MetricRelabelConfigs) == 0 {
+				return
+			}
+			if relabeled, _, _, keep := relabelSample(name, nil, nil, module.MetricRelabelConfigs); keep {
+				metadata[relabeled] = md
+			}
+		}
+		for _, metric := range module.Metrics {
+			switch metric.Type {
+			case "EnumAsStateSet", "Bits":
+				add(metric.Name, FamilyMetadata{Type: "stateset"})
+			case "EnumAsInfo":
+				add(metric.Name+"_info", FamilyMetadata{Type: "info"})
+			default:
+				if metric.Unit != "" {
+					add(metric.Name, FamilyMetadata{Unit: metric.Unit})
+				}
+			}
+		}
+	}
+	return metadata
+}
+
+// A counter with the time it was created, exposed as _created.
+type createdCounter struct {
+	prometheus.Metric
+	created time.Time
+}
+
+func (c createdCounter) Write(m *dto.Metric) error {
+	if err := c.Metric.Write(m); err != nil {
+		return err
+	}
+	if m.Counter != nil {
+		m.Counter.CreatedTimestamp = timestamppb.New(c.created)
+	}
+	return nil
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// WriteOpenMetrics writes metric families in the OpenMetrics text format,
+// using the metadata for stateset and info families and units. Counters
+// get the _total suffix OpenMetrics requires, and _created if known.
+func WriteOpenMetrics(out io.Writer, mfs []*dto.MetricFamily, metadata map[string]FamilyMetadata) error {
+	w := bufio.NewWriter(out)
+	for _, mf := range mfs {
+		name := mf.GetName()
+		md := metadata[name]
+		family, typ := name, ""
+		switch {
+		case md.Type == "info" && strings.HasSuffix(name, "_info"):
+			family, typ = strings.TrimSuffix(name, "_info"), "info"
+		case md.Type == "stateset":
+			typ = "stateset"
+		case mf.GetType() == dto.MetricType_COUNTER:
+			family, typ = strings.TrimSuffix(name, "_total"), "counter"
+		case mf.GetType() == dto.MetricType_GAUGE:
+			typ = "gauge"
+		case mf.GetType() == dto.MetricType_UNTYPED:
+			typ = "unknown"
+		default:
+			// Summaries and histograms are handled by the client library.
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if _, err := expfmt.MetricFamilyToOpenMetrics(out, mf); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if mf.Help != nil {
+			w.WriteString("# HELP " + family + " " + openMetricsEscaper.Replace(mf.GetHelp()) + "\n")
+		}
+		w.WriteString("# TYPE " + family + " " + typ + "\n")
+		// OpenMetrics requires the name to end with the unit.
+		if md.Unit != "" && (typ == "counter" || typ == "gauge" || typ == "unknown") && strings.HasSuffix(family, "_"+md.Unit) {
+			w.WriteString("# UNIT " + family + " " + md.Unit + "\n")
+		}
+
+		for _, m := range mf.Metric {
+			switch typ {
+			case "counter":
+				writeOpenMetricsSample(w, family+"_total", m, m.GetCounter().GetValue())
+				if ct := m.GetCounter().GetCreatedTimestamp(); ct != nil {
+					created := float64(ct.AsTime().UnixNano()) / 1e9
+					writeOpenMetricsSample(w, family+"_created", &dto.Metric{Label: m.Label, TimestampMs: m.TimestampMs}, created)
+				}
+			case "info":
+				writeOpenMetricsSample(w, family+"_info", m, 1)
+			case "unknown":
+				writeOpenMetricsSample(w, family, m, m.GetUntyped().GetValue())
+			default:
+				writeOpenMetricsSample(w, family, m, m.GetGauge().GetValue())
+			}
+		}
+	}
+	w.WriteString("# EOF\n")
+	return w.Flush()
+}
+
+func writeOpenMetricsSample(w *bufio.Writer, name string, m *dto.Metric, value float64) {
+	w.WriteString(name)
+	if len(m.Label) > 0 {
+		w.WriteByte('{')
+		for i, l := range m.Label {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l.GetName() + `="` + openMetricsEscaper.Replace(l.GetValue()) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatOpenMetricsFloat(value))
+	if m.TimestampMs != nil {
+		w.WriteByte(' ')
+		w.WriteString(formatOpenMetricsFloat(float64(m.GetTimestampMs()) / 1000))
+	}
+	w.WriteByte('\n')
+}
+
+func formatOpenMetricsFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, "e.") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+type constCollector []prometheus.Metric
+
+func (c constCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (c constCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c {
+		ch <- m
+	}
+}
+
+func TestOpenMetricsMetadata(t *testing.T) {
+	modules := []*NamedModule{
+		{
+			Module: &config.Module{
+				Metrics: []*config.Metric{
+					{Name: "ifOperStatus", Type: "EnumAsStateSet"},
+					{Name: "ifType", Type: "EnumAsInfo"},
+					{Name: "ifFlags", Type: "Bits"},
+					{Name: "ifDescr", Type: "DisplayString"},
+					{Name: "sysUpTime_seconds", Type: "gauge", Unit: "seconds"},
+				},
+			},
+			name: "if_mib",
+		},
+	}
+	expected := map[string]FamilyMetadata{
+		"ifOperStatus":      {Type: "stateset"},
+		"ifType_info":       {Type: "info"},
+		"ifFlags":           {Type: "stateset"},
+		"sysUpTime_seconds": {Unit: "seconds"},
+	}
+	got := OpenMetricsMetadata(modules)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got metadata %v, want %v", got, expected)
+	}
+}
+
+func TestOpenMetricsMetadataRelabeled(t *testing.T) {
+	var cfgs []*config.RelabelConfig
+	err := yaml.UnmarshalStrict([]byte(`[{source_labels: [__name__], regex: "(if|sys)(.*)", target_label: __name__, replacement: "device_$2"}]`), &cfgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := []*NamedModule{
+		{
+			Module: &config.Module{
+				Metrics: []*config.Metric{
+					{Name: "ifOperStatus", Type: "EnumAsStateSet"},
+					{Name: "ifType", Type: "EnumAsInfo"},
+					{Name: "sysUpTime_seconds", Type: "gauge", Unit: "seconds"},
+				},
+				MetricRelabelConfigs: cfgs,
+			},
+			name: "if_mib",
+		},
+	}
+	expected := map[string]FamilyMetadata{
+		"ifOperStatus":          {Type: "stateset"},
+		"device_OperStatus":     {Type: "stateset"},
+		"ifType_info":           {Type: "info"},
+		"device_Type_info":      {Type: "info"},
+		"sysUpTime_seconds":     {Unit: "seconds"},
+		"device_UpTime_seconds": {Unit: "seconds"},
+	}
+	got := OpenMetricsMetadata(modules)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got metadata %v, want %v", got, expected)
+	}
+}
+
+func TestCreatedCounter(t *testing.T) {
+	desc := prometheus.NewDesc("ifInOctets", "", nil, nil)
+	created := time.Unix(1700000000, 0)
+
+	counter := createdCounter{Metric: prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 5), created: created}
+	m := &dto.Metric{}
+	if err := counter.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if !m.GetCounter().GetCreatedTimestamp().AsTime().Equal(created) {
+		t.Errorf("Got created timestamp %v, want %v", m.GetCounter().GetCreatedTimestamp(), created)
+	}
+
+	// Only counters have a created timestamp.
+	gauge := createdCounter{Metric: prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 5), created: created}
+	m = &dto.Metric{}
+	if err := gauge.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Counter != nil {
+		t.Errorf("Got counter for a gauge: %v", m)
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	indexes := []string{"ifIndex"}
+	octets := prometheus.NewDesc("ifInOctets", "The total number of octets received on the interface.", indexes, nil)
+	operStatus := &config.Metric{Name: "ifOperStatus", Help: "The current operational state of the interface.", Type: "EnumAsStateSet",
+		EnumValues: map[int]string{1: "up", 2: "down"}}
+	ifType := &config.Metric{Name: "ifType", Help: "The type of interface.", Type: "EnumAsInfo",
+		EnumValues: map[int]string{6: "ethernetCsmacd"}}
+	uptime := prometheus.NewDesc("sysUpTime_seconds", "The time since the \"agent\" was started.", nil, nil)
+	alias := prometheus.NewDesc("ifAlias", "An alias.", indexes, nil)
+
+	metrics := constCollector{
+		createdCounter{Metric: prometheus.MustNewConstMetric(octets, prometheus.CounterValue, 5, "1"), created: time.Unix(1700000000, 0)},
+		prometheus.MustNewConstMetric(octets, prometheus.CounterValue, 7, "2"),
+		prometheus.MustNewConstMetric(uptime, prometheus.GaugeValue, 12.5),
+		prometheus.MustNewConstMetric(alias, prometheus.UntypedValue, 1, "a\\b"),
+	}
+	metrics = append(metrics, enumAsStateSet(operStatus, 1, indexes, []string{"1"}, nil)...)
+	metrics = append(metrics, enumAsInfo(ifType, 6, indexes, []string{"1"}, nil)...)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics)
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]FamilyMetadata{
+		"ifOperStatus":      {Type: "stateset"},
+		"ifType_info":       {Type: "info"},
+		"sysUpTime_seconds": {Unit: "seconds"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteOpenMetrics(&buf, mfs, metadata); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP ifAlias An alias.
+# TYPE ifAlias unknown
+ifAlias{ifIndex="a\\b"} 1.0
+# HELP ifInOctets The total number of octets received on the interface.
+# TYPE ifInOctets counter
+ifInOctets_total{ifIndex="1"} 5.0
+ifInOctets_created{ifIndex="1"} 1.7e+09
+ifInOctets_total{ifIndex="2"} 7.0
+# HELP ifOperStatus The current operational state of the interface. (EnumAsStateSet)
+# TYPE ifOperStatus stateset
+ifOperStatus{ifIndex="1",ifOperStatus="down"} 0.0
+ifOperStatus{ifIndex="1",ifOperStatus="up"} 1.0
+# HELP ifType The type of interface. (EnumAsInfo)
+# TYPE ifType info
+ifType_info{ifIndex="1",ifType="ethernetCsmacd"} 1.0
+# HELP sysUpTime_seconds The time since the \"agent\" was started.
+# TYPE sysUpTime_seconds gauge
+# UNIT sysUpTime_seconds seconds
+sysUpTime_seconds 12.5
+# EOF
+`
+	if buf.String() != expected {
+		t.Errorf("Got:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
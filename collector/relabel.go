@@ -0,0 +1,126 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+// Create a sample, with the name and labels left by the module's relabel
+// configs. The sample is nil if they drop it.
+func newConstMetric(name, help string, labelnames, labelvalues []string, t prometheus.ValueType, value float64, cfgs []*config.RelabelConfig) (prometheus.Metric, error) {
+	if len(cfgs) > 0 {
+		var keep bool
+		name, labelnames, labelvalues, keep = relabelSample(name, labelnames, labelvalues, cfgs)
+		if !keep {
+			return nil, nil
+		}
+	}
+	return prometheus.NewConstMetric(prometheus.NewDesc(name, help, labelnames, nil), t, value, labelvalues...)
+}
+
+// Apply relabel configs to the name and labels of a sample, returning
+// false if it is to be dropped. The labels are returned sorted by name.
+func relabelSample(name string, labelnames, labelvalues []string, cfgs []*config.RelabelConfig) (string, []string, []string, bool) {
+	labels := make(map[string]string, len(labelnames)+1)
+	for i, ln := range labelnames {
+		labels[ln] = labelvalues[i]
+	}
+	labels[model.MetricNameLabel] = name
+
+	if !relabel(labels, cfgs) {
+		return "", nil, nil, false
+	}
+
+	name = labels[model.MetricNameLabel]
+	delete(labels, model.MetricNameLabel)
+	labelnames = make([]string, 0, len(labels))
+	for k := range labels {
+		labelnames = append(labelnames, k)
+	}
+	sort.Strings(labelnames)
+	labelvalues = make([]string, 0, len(labels))
+	for _, k := range labelnames {
+		labelvalues = append(labelvalues, labels[k])
+	}
+	return name, labelnames, labelvalues, true
+}
+
+// Apply relabel configs to a label set in place, returning false if
+// the labels are to be dropped.
+func relabel(labels map[string]string, cfgs []*config.RelabelConfig) bool {
+	for _, cfg := range cfgs {
+		values := make([]string, 0, len(cfg.SourceLabels))
+		for _, ln := range cfg.SourceLabels {
+			values = append(values, labels[ln])
+		}
+		val := strings.Join(values, cfg.Separator)
+
+		switch cfg.Action {
+		case config.RelabelDrop:
+			if cfg.Regex.MatchString(val) {
+				return false
+			}
+		case config.RelabelKeep:
+			if !cfg.Regex.MatchString(val) {
+				return false
+			}
+		case config.RelabelReplace:
+			indexes := cfg.Regex.FindStringSubmatchIndex(val)
+			if indexes == nil {
+				continue
+			}
+			target := string(cfg.Regex.ExpandString(nil, cfg.TargetLabel, val, indexes))
+			if !model.LabelName(target).IsValid() {
+				continue
+			}
+			res := string(cfg.Regex.ExpandString(nil, cfg.Replacement, val, indexes))
+			if res == "" {
+				delete(labels, target)
+				continue
+			}
+			labels[target] = res
+		case config.RelabelHashMod:
+			sum := md5.Sum([]byte(val))
+			labels[cfg.TargetLabel] = strconv.FormatUint(binary.BigEndian.Uint64(sum[8:])%cfg.Modulus, 10)
+		case config.RelabelLabelMap:
+			// The metric name is never renamed or dropped by label name rules.
+			mapped := map[string]string{}
+			for ln, lv := range labels {
+				if ln != model.MetricNameLabel && cfg.Regex.MatchString(ln) {
+					mapped[cfg.Regex.ReplaceAllString(ln, cfg.Replacement)] = lv
+				}
+			}
+			for ln, lv := range mapped {
+				labels[ln] = lv
+			}
+		case config.RelabelLabelDrop:
+			for ln := range labels {
+				if ln != model.MetricNameLabel && cfg.Regex.MatchString(ln) {
+					delete(labels, ln)
+				}
+			}
+		}
+	}
+	return true
+}
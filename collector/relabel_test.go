@@ -0,0 +1,126 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+func TestRelabelSample(t *testing.T) {
+	cases := []struct {
+		config string
+		name   string
+		labels map[string]string
+	}{
+		{
+			config: ``,
+			name:   "ifInOctets",
+			labels: map[string]string{"ifDescr": "eth0", "ifIndex": "2"},
+		},
+		{
+			config: `[{source_labels: [ifDescr], regex: "lo|eth0", action: drop}]`,
+		},
+		{
+			config: `[{source_labels: [ifDescr], regex: "lo", action: drop}]`,
+			name:   "ifInOctets",
+			labels: map[string]string{"ifDescr": "eth0", "ifIndex": "2"},
+		},
+		{
+			config: `[{source_labels: [__name__], regex: "ifIn.*", action: keep}]`,
+			name:   "ifInOctets",
+			labels: map[string]string{"ifDescr": "eth0", "ifIndex": "2"},
+		},
+		{
+			config: `[{source_labels: [__name__], regex: "ifOut.*", action: keep}]`,
+		},
+		{
+			config: `[{source_labels: [ifDescr, ifIndex], regex: "eth(.*);(.*)", target_label: port, replacement: "$1/$2"}]`,
+			name:   "ifInOctets",
+			labels: map[string]string{"ifDescr": "eth0", "ifIndex": "2", "port": "0/2"},
+		},
+		{
+			// Renaming the metric.
+			config: `[{source_labels: [__name__], regex: "if(.*)", target_label: __name__, replacement: "interface_$1"}]`,
+			name:   "interface_InOctets",
+			labels: map[string]string{"ifDescr": "eth0", "ifIndex": "2"},
+		},
+		{
+			// An empty replacement removes the label.
+			config: `[{target_label: ifDescr, replacement: ""}]`,
+			name:   "ifInOctets",
+			labels: map[string]string{"ifIndex": "2"},
+		},
+		{
+			config: `[{source_labels: [ifDescr], target_label: shard, modulus: 4, action: hashmod}]`,
+			name:   "ifInOctets",
+			labels: map[string]string{"ifDescr": "eth0", "ifIndex": "2", "shard": "1"},
+		},
+		{
+			config: `[{regex: "if(.*)", replacement: "interface_$1", action: labelmap}, {regex: "if.*", action: labeldrop}]`,
+			name:   "ifInOctets",
+			labels: map[string]string{"interface_Descr": "eth0", "interface_Index": "2"},
+		},
+	}
+	for i, c := range cases {
+		var cfgs []*config.RelabelConfig
+		if err := yaml.UnmarshalStrict([]byte(c.config), &cfgs); err != nil {
+			t.Fatalf("Error unmarshalling relabel config %d: %s", i, err)
+		}
+		sample, err := newConstMetric("ifInOctets", "", []string{"ifIndex", "ifDescr"}, []string{"2", "eth0"},
+			prometheus.CounterValue, 7, cfgs)
+		if err != nil {
+			t.Fatalf("newConstMetric %d: %s", i, err)
+		}
+		if sample == nil {
+			if c.name != "" {
+				t.Errorf("newConstMetric %d: sample dropped, want %s%v", i, c.name, c.labels)
+			}
+			continue
+		}
+		if c.name == "" {
+			t.Errorf("newConstMetric %d: sample not dropped", i)
+			continue
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(constCollector{sample})
+		mfs, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("newConstMetric %d: %s", i, err)
+		}
+		if len(mfs) != 1 || len(mfs[0].Metric) != 1 {
+			t.Fatalf("newConstMetric %d: got %v, want a single sample", i, mfs)
+		}
+		if mfs[0].GetName() != c.name {
+			t.Errorf("newConstMetric %d: got name %s, want %s", i, mfs[0].GetName(), c.name)
+		}
+		m := mfs[0].Metric[0]
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if !reflect.DeepEqual(labels, c.labels) {
+			t.Errorf("newConstMetric %d: got labels %v, want %v", i, labels, c.labels)
+		}
+		if m.GetCounter().GetValue() != 7 {
+			t.Errorf("newConstMetric %d: got value %v, want 7", i, m.GetCounter().GetValue())
+		}
+	}
+}
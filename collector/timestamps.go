@@ -0,0 +1,86 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+// The explicit timestamps of the samples of a scrape. A nil
+// *sampleTimestamps gives no timestamps.
+type sampleTimestamps struct {
+	// When each PDU was received, by oid, for the received source.
+	received map[string]time.Time
+	// The time for samples not from a single PDU, such as computed ones.
+	// When the last PDU was received, or the time of the device clock.
+	scrape time.Time
+	oldest time.Time
+}
+
+func newSampleTimestamps(module *config.Module, results ScrapeResults, oidToPdu map[string]gosnmp.SnmpPDU, now time.Time, logger log.Logger) *sampleTimestamps {
+	if module.Timestamps == nil {
+		return nil
+	}
+	s := &sampleTimestamps{oldest: now.Add(-module.Timestamps.MaxAge)}
+	switch module.Timestamps.Source {
+	case "received":
+		s.received = make(map[string]time.Time, len(results.received))
+		for i, t := range results.received {
+			s.received[results.pdus[i].Name[1:]] = t
+			if t.After(s.scrape) {
+				s.scrape = t
+			}
+		}
+	case "device_clock":
+		pdu, ok := oidToPdu[module.Timestamps.ClockOid]
+		if !ok {
+			level.Debug(logger).Log("msg", "Device clock not returned, not setting timestamps", "oid", module.Timestamps.ClockOid)
+			return nil
+		}
+		clock, err := parseDateAndTime(&pdu)
+		if err != nil {
+			level.Debug(logger).Log("msg", "Error parsing device clock, not setting timestamps", "oid", module.Timestamps.ClockOid, "err", err)
+			return nil
+		}
+		s.scrape = time.Unix(int64(clock), 0)
+		if s.scrape.Before(s.oldest) {
+			level.Debug(logger).Log("msg", "Device clock too far in the past, not setting timestamps", "clock", s.scrape, "max_age", module.Timestamps.MaxAge)
+			return nil
+		}
+	}
+	return s
+}
+
+// The timestamp of the samples of the PDU with the given oid, or of samples
+// not from a single PDU for an empty oid. Zero for no timestamp, including
+// when it is too far in the past.
+func (s *sampleTimestamps) forOid(oid string) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	t := s.scrape
+	if oid != "" && s.received != nil {
+		t = s.received[oid]
+	}
+	if t.Before(s.oldest) {
+		return time.Time{}
+	}
+	return t
+}
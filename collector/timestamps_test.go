@@ -0,0 +1,94 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+func TestSampleTimestamps(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	received := &config.Timestamps{Source: "received", MaxAge: 5 * time.Minute}
+	clock := &config.Timestamps{Source: "device_clock", ClockOid: "1.3.6.1.2.1.25.1.2.0", MaxAge: 5 * time.Minute}
+	// 2023-11-14 22:12:20 UTC, 1699999940.
+	clockPdu := gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.25.1.2.0", Type: gosnmp.OctetString, Value: []byte{7, 231, 11, 14, 22, 12, 20, 0}}
+	// 2023-11-14 21:00:00 UTC, over an hour before now.
+	oldClockPdu := gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.25.1.2.0", Type: gosnmp.OctetString, Value: []byte{7, 231, 11, 14, 21, 0, 0, 0}}
+	results := ScrapeResults{
+		pdus: []gosnmp.SnmpPDU{
+			{Name: ".1.1.1.1", Type: gosnmp.Integer, Value: 1},
+			{Name: ".1.1.1.2", Type: gosnmp.Integer, Value: 2},
+			{Name: ".1.1.1.3", Type: gosnmp.Integer, Value: 3},
+		},
+		received: []time.Time{now.Add(-time.Hour), now.Add(-2 * time.Second), now.Add(-time.Second)},
+	}
+
+	cases := []struct {
+		name       string
+		timestamps *config.Timestamps
+		pdus       []gosnmp.SnmpPDU
+		expected   map[string]time.Time
+	}{
+		{
+			name:     "none",
+			expected: map[string]time.Time{"1.1.1.2": {}, "": {}},
+		},
+		{
+			name:       "received",
+			timestamps: received,
+			expected: map[string]time.Time{
+				// Too old.
+				"1.1.1.1": {},
+				"1.1.1.2": now.Add(-2 * time.Second),
+				"1.1.1.3": now.Add(-time.Second),
+				"":        now.Add(-time.Second),
+			},
+		},
+		{
+			name:       "device clock",
+			timestamps: clock,
+			pdus:       []gosnmp.SnmpPDU{clockPdu},
+			expected:   map[string]time.Time{"1.1.1.1": now.Add(-time.Minute), "": now.Add(-time.Minute)},
+		},
+		{
+			name:       "device clock too old",
+			timestamps: clock,
+			pdus:       []gosnmp.SnmpPDU{oldClockPdu},
+			expected:   map[string]time.Time{"1.1.1.1": {}, "": {}},
+		},
+		{
+			name:       "device clock missing",
+			timestamps: clock,
+			expected:   map[string]time.Time{"1.1.1.1": {}, "": {}},
+		},
+	}
+	for _, c := range cases {
+		oidToPdu := map[string]gosnmp.SnmpPDU{}
+		for _, pdu := range append(results.pdus, c.pdus...) {
+			oidToPdu[pdu.Name[1:]] = pdu
+		}
+		s := newSampleTimestamps(&config.Module{Timestamps: c.timestamps}, results, oidToPdu, now, log.NewNopLogger())
+		for oid, want := range c.expected {
+			if got := s.forOid(oid); !got.Equal(want) {
+				t.Errorf("%s: timestamp of %q: got %v, want %v", c.name, oid, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+// Mismatches already warned about, so each is only logged once.
+var warnedWireTypes sync.Map
+
+// The wire types seen for each metric of a module in a scrape.
+type wireTypes struct {
+	module  string
+	metrics []*config.Metric
+	types   map[*config.Metric]map[gosnmp.Asn1BER]struct{}
+}
+
+func newWireTypes(module string) *wireTypes {
+	return &wireTypes{module: module, types: map[*config.Metric]map[gosnmp.Asn1BER]struct{}{}}
+}
+
+// Record the wire type of a value of a metric, warning the first time it
+// contradicts the type of the metric.
+func (w *wireTypes) add(metric *config.Metric, t gosnmp.Asn1BER, logger log.Logger) {
+	seen, ok := w.types[metric]
+	if !ok {
+		seen = map[gosnmp.Asn1BER]struct{}{}
+		w.types[metric] = seen
+		w.metrics = append(w.metrics, metric)
+	}
+	if _, ok := seen[t]; ok {
+		return
+	}
+	seen[t] = struct{}{}
+	if !wireTypeMismatch(metric, t) {
+		return
+	}
+	key := w.module + "\x00" + metric.Name + "\x00" + asn1TypeName(t)
+	if _, warned := warnedWireTypes.LoadOrStore(key, struct{}{}); !warned {
+		level.Warn(logger).Log("msg", "Wire type of value contradicts the type of the metric", "metric", metric.Name, "oid", metric.Oid, "type", metric.Type, "asn1_type", asn1TypeName(t))
+	}
+}
+
+// The snmp_metric_info series, one for each wire type of each metric.
+func (w *wireTypes) samples() []prometheus.Metric {
+	desc := prometheus.NewDesc("snmp_metric_info", "The ASN.1 types SNMP values of metrics were received as.",
+		[]string{"metric", "oid", "asn1_type"}, prometheus.Labels{"module": w.module})
+	results := []prometheus.Metric{}
+	for _, metric := range w.metrics {
+		types := make([]string, 0, len(w.types[metric]))
+		for t := range w.types[metric] {
+			types = append(types, asn1TypeName(t))
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			results = append(results, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, metric.Name, metric.Oid, t))
+		}
+	}
+	return results
+}
+
+func isIntegerWireType(t gosnmp.Asn1BER) bool {
+	switch t {
+	case gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Counter64, gosnmp.Uinteger32,
+		opaqueCounter64, opaqueInteger64, opaqueUnsigned64:
+		return true
+	}
+	return false
+}
+
+// Whether a wire type contradicts the type of a metric. Types that can
+// come from several wire types, such as the InetAddress ones, are not
+// checked, nor are the responses for missing objects.
+func wireTypeMismatch(metric *config.Metric, t gosnmp.Asn1BER) bool {
+	switch t {
+	case gosnmp.Null, gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+		return false
+	}
+	switch metric.Type {
+	case "counter":
+		if metric.Combine != "" {
+			return !isIntegerWireType(t)
+		}
+		return t != gosnmp.Counter32 && t != gosnmp.Counter64 && t != opaqueCounter64
+	case "gauge":
+		return !isIntegerWireType(t) && t != gosnmp.OpaqueFloat && t != gosnmp.OpaqueDouble
+	case "Float", "Double":
+		return t != gosnmp.OpaqueFloat && t != gosnmp.OpaqueDouble
+	case "TimeTicks", "TimeTicksAsTimestamp":
+		return t != gosnmp.TimeTicks
+	case "EnumAsInfo", "EnumAsStateSet":
+		return !isIntegerWireType(t)
+	case "OctetString", "DisplayString", "PhysAddress48", "DateAndTime", "Bits":
+		return t != gosnmp.OctetString
+	case "ObjectIdentifier":
+		return t != gosnmp.ObjectIdentifier
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/gosnmp/gosnmp"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/prometheus/snmp_exporter/config"
+)
+
+func TestWireTypeMismatch(t *testing.T) {
+	cases := []struct {
+		metric   *config.Metric
+		wireType gosnmp.Asn1BER
+		mismatch bool
+	}{
+		{metric: &config.Metric{Type: "counter"}, wireType: gosnmp.Counter32},
+		{metric: &config.Metric{Type: "counter"}, wireType: gosnmp.Counter64},
+		{metric: &config.Metric{Type: "counter"}, wireType: gosnmp.Gauge32, mismatch: true},
+		{metric: &config.Metric{Type: "counter"}, wireType: opaqueCounter64},
+		{metric: &config.Metric{Type: "gauge"}, wireType: opaqueInteger64},
+		{metric: &config.Metric{Type: "counter", Combine: "1.2"}, wireType: gosnmp.Gauge32},
+		{metric: &config.Metric{Type: "gauge"}, wireType: gosnmp.TimeTicks},
+		{metric: &config.Metric{Type: "gauge"}, wireType: gosnmp.Counter64},
+		{metric: &config.Metric{Type: "gauge"}, wireType: gosnmp.OctetString, mismatch: true},
+		{metric: &config.Metric{Type: "gauge"}, wireType: gosnmp.NoSuchInstance},
+		{metric: &config.Metric{Type: "Float"}, wireType: gosnmp.OpaqueFloat},
+		{metric: &config.Metric{Type: "Double"}, wireType: gosnmp.Integer, mismatch: true},
+		{metric: &config.Metric{Type: "TimeTicks"}, wireType: gosnmp.TimeTicks},
+		{metric: &config.Metric{Type: "TimeTicksAsTimestamp"}, wireType: gosnmp.Gauge32, mismatch: true},
+		{metric: &config.Metric{Type: "EnumAsInfo"}, wireType: gosnmp.Integer},
+		{metric: &config.Metric{Type: "DisplayString"}, wireType: gosnmp.OctetString},
+		{metric: &config.Metric{Type: "DisplayString"}, wireType: gosnmp.Integer, mismatch: true},
+		{metric: &config.Metric{Type: "ObjectIdentifier"}, wireType: gosnmp.OctetString, mismatch: true},
+		{metric: &config.Metric{Type: "InetAddress"}, wireType: gosnmp.OctetString},
+	}
+	for _, c := range cases {
+		got := wireTypeMismatch(c.metric, c.wireType)
+		if got != c.mismatch {
+			t.Errorf("wireTypeMismatch(%s, %s): got %v, want %v", c.metric.Type, c.wireType, got, c.mismatch)
+		}
+	}
+}
+
+func TestWireTypesSamples(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogfmtLogger(&buf)
+	octets := &config.Metric{Name: "ifInOctets", Oid: "1.3.6.1.2.1.2.2.1.10", Type: "counter"}
+	speed := &config.Metric{Name: "ifSpeed", Oid: "1.3.6.1.2.1.2.2.1.5", Type: "counter"}
+
+	for i := 0; i < 2; i++ {
+		types := newWireTypes("test_wire_types")
+		types.add(octets, gosnmp.Counter32, logger)
+		types.add(octets, gosnmp.Counter32, logger)
+		types.add(speed, gosnmp.Gauge32, logger)
+
+		expected := []string{
+			`Desc{fqName: "snmp_metric_info", help: "The ASN.1 types SNMP values of metrics were received as.", constLabels: {module="test_wire_types"}, variableLabels: {metric,oid,asn1_type}} label:{name:"asn1_type" value:"Counter32"} label:{name:"metric" value:"ifInOctets"} label:{name:"module" value:"test_wire_types"} label:{name:"oid" value:"1.3.6.1.2.1.2.2.1.10"} gauge:{value:1}`,
+			`Desc{fqName: "snmp_metric_info", help: "The ASN.1 types SNMP values of metrics were received as.", constLabels: {module="test_wire_types"}, variableLabels: {metric,oid,asn1_type}} label:{name:"asn1_type" value:"Gauge32"} label:{name:"metric" value:"ifSpeed"} label:{name:"module" value:"test_wire_types"} label:{name:"oid" value:"1.3.6.1.2.1.2.2.1.5"} gauge:{value:1}`,
+		}
+		samples := types.samples()
+		if len(samples) != len(expected) {
+			t.Fatalf("Got %d samples, want %d", len(samples), len(expected))
+		}
+		for j, sample := range samples {
+			m := &dto.Metric{}
+			if err := sample.Write(m); err != nil {
+				t.Fatalf("Error writing metric: %v", err)
+			}
+			got := strings.ReplaceAll(sample.Desc().String()+" "+m.String(), "  ", " ")
+			if got != expected[j] {
+				t.Errorf("Got metric: %s, want %s", got, expected[j])
+			}
+		}
+	}
+
+	// The mismatch of ifSpeed is only logged once, across scrapes.
+	if n := strings.Count(buf.String(), "metric=ifSpeed"); n != 1 {
+		t.Errorf("Got %d warnings for ifSpeed, want 1: %s", n, buf.String())
+	}
+	if strings.Contains(buf.String(), "metric=ifInOctets") {
+		t.Errorf("Unexpected warning for ifInOctets: %s", buf.String())
+	}
+}
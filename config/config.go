@@ -18,9 +18,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/common/model"
+	"golang.org/x/text/encoding/ianaindex"
 	"gopkg.in/yaml.v2"
 )
 
@@ -68,6 +71,14 @@ var (
 	DefaultRegexpExtract = RegexpExtract{
 		Value: "$1",
 	}
+	DefaultExtendCounters = ExtendCounters{
+		// sysUpTime.0
+		UptimeOid: "1.3.6.1.2.1.1.3.0",
+	}
+	DefaultTimestamps = Timestamps{
+		Source: "received",
+		MaxAge: 5 * time.Minute,
+	}
 )
 
 // Config for the snmp_exporter.
@@ -92,12 +103,432 @@ type Module struct {
 	Metrics    []*Metric       `yaml:"metrics"`
 	WalkParams WalkParams      `yaml:",inline"`
 	Filters    []DynamicFilter `yaml:"filters,omitempty"`
+	// Character set of string values, used by metrics that don't set their own.
+	Encoding string `yaml:"encoding,omitempty"`
+	// Rendering of MAC addresses and hex strings, used by metrics that don't set their own.
+	Formats `yaml:",inline"`
+	// Relabeling applied to the module's samples before they are exposed.
+	MetricRelabelConfigs []*RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
+	// Labels added to all samples of the module.
+	StaticLabels map[string]string `yaml:"static_labels,omitempty"`
+	ScalarLabels []*ScalarLabel    `yaml:"scalar_labels,omitempty"`
+	// Metrics computed from the values of other metrics in the same row.
+	ComputedMetrics []*ComputedMetric `yaml:"computed_metrics,omitempty"`
+	// Keep Counter32 values between scrapes to extend them to 64 bits.
+	ExtendCounters *ExtendCounters `yaml:"extend_counters,omitempty"`
+	// Expose the ASN.1 types of the values of each metric as snmp_metric_info.
+	MetricInfo bool `yaml:"metric_info,omitempty"`
+	// Expose samples with explicit timestamps rather than the time of the scrape.
+	Timestamps *Timestamps `yaml:"timestamps,omitempty"`
+}
+
+// Timestamps are explicit timestamps of the samples of a module, for
+// values older than the scrape, such as from slow walks or agents that
+// cache them.
+type Timestamps struct {
+	// received for the time each PDU was received, or device_clock for
+	// the time of the clock_oid.
+	Source string `yaml:"source,omitempty"`
+	// A DateAndTime clock of the agent, such as hrSystemDate.0.
+	ClockOid string `yaml:"clock_oid,omitempty"`
+	// Timestamps further in the past are dropped, the samples being
+	// exposed without one.
+	MaxAge time.Duration `yaml:"max_age,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Timestamps) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultTimestamps
+	type plain Timestamps
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	switch c.Source {
+	case "received":
+	case "device_clock":
+		if c.ClockOid == "" {
+			return fmt.Errorf("timestamps from the device_clock require a clock_oid")
+		}
+	default:
+		return fmt.Errorf("unknown timestamps source '%s', must be received or device_clock", c.Source)
+	}
+	if c.MaxAge <= 0 {
+		return fmt.Errorf("timestamps max_age must be positive")
+	}
+	return nil
+}
+
+// ExtendCounters extends Counter32 metrics to 64 bits by keeping their
+// previous values for each target, using the agent's uptime to tell a
+// wrap from a restart.
+type ExtendCounters struct {
+	// A TimeTicks uptime such as sysUpTime.0, or an uptime in seconds
+	// such as snmpEngineTime.0.
+	UptimeOid string `yaml:"uptime_oid,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *ExtendCounters) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultExtendCounters
+	type plain ExtendCounters
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.UptimeOid == "" {
+		return fmt.Errorf("extend_counters requires an uptime_oid")
+	}
+	return nil
+}
+
+// ComputedMetric is a metric whose value is an expression over metrics of
+// the module with the same indexes, evaluated for every row where all of
+// them have a value.
+type ComputedMetric struct {
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+	Type string `yaml:"type"`
+	Expr Expr   `yaml:"expr"`
+}
+
+// ScalarLabel is a scalar object, such as sysName.0, whose value is added
+// as a label to all samples of a module.
+type ScalarLabel struct {
+	Labelname   string `yaml:"labelname"`
+	Oid         string `yaml:"oid"`
+	Type        string `yaml:"type"`
+	DisplayHint string `yaml:"display_hint,omitempty"`
+	Encoding    string `yaml:"encoding,omitempty"`
+	Formats     `yaml:",inline"`
 }
 
 func (c *Module) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	*c = DefaultModule
 	type plain Module
-	return unmarshal((*plain)(c))
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkEncoding(c.Encoding); err != nil {
+		return err
+	}
+	if err := c.Formats.check(); err != nil {
+		return err
+	}
+	// Metrics, indexes and lookups inherit the encoding and formats unless
+	// they set their own.
+	for _, metric := range c.Metrics {
+		if err := checkEncoding(metric.Encoding); err != nil {
+			return err
+		}
+		if err := metric.Formats.check(); err != nil {
+			return err
+		}
+		if metric.Combine != "" && metric.Type != "counter" && metric.Type != "gauge" {
+			return fmt.Errorf("metric %s combines columns but has type %s, must be counter or gauge", metric.Name, metric.Type)
+		}
+		if metric.Encoding == "" {
+			metric.Encoding = c.Encoding
+		}
+		metric.Formats = metric.Formats.inherit(c.Formats)
+		for _, index := range metric.Indexes {
+			if err := index.Formats.check(); err != nil {
+				return err
+			}
+			index.Formats = index.Formats.inherit(metric.Formats)
+		}
+		for _, lookup := range metric.Lookups {
+			if err := checkEncoding(lookup.Encoding); err != nil {
+				return err
+			}
+			if err := lookup.Formats.check(); err != nil {
+				return err
+			}
+			if lookup.Encoding == "" {
+				lookup.Encoding = metric.Encoding
+			}
+			lookup.Formats = lookup.Formats.inherit(metric.Formats)
+		}
+		lookups, err := sortLookups(metric.Lookups)
+		if err != nil {
+			return fmt.Errorf("metric %s: %s", metric.Name, err)
+		}
+		metric.Lookups = lookups
+	}
+	for _, scalar := range c.ScalarLabels {
+		if err := checkEncoding(scalar.Encoding); err != nil {
+			return err
+		}
+		if err := scalar.Formats.check(); err != nil {
+			return err
+		}
+		if scalar.Encoding == "" {
+			scalar.Encoding = c.Encoding
+		}
+		scalar.Formats = scalar.Formats.inherit(c.Formats)
+	}
+	if err := c.checkComputedMetrics(); err != nil {
+		return err
+	}
+	return c.checkModuleLabels()
+}
+
+// Check that computed metrics have unique names and only use numeric
+// metrics of the module that share their indexes.
+func (c *Module) checkComputedMetrics() error {
+	metrics := make(map[string]*Metric, len(c.Metrics))
+	for _, metric := range c.Metrics {
+		metrics[metric.Name] = metric
+	}
+	computed := map[string]struct{}{}
+	for _, cm := range c.ComputedMetrics {
+		if !model.IsValidMetricName(model.LabelValue(cm.Name)) {
+			return fmt.Errorf("invalid computed metric name '%s'", cm.Name)
+		}
+		if _, ok := metrics[cm.Name]; ok {
+			return fmt.Errorf("computed metric %s has the name of a walked metric", cm.Name)
+		}
+		if _, ok := computed[cm.Name]; ok {
+			return fmt.Errorf("duplicate computed metric %s", cm.Name)
+		}
+		computed[cm.Name] = struct{}{}
+		if cm.Type != "counter" && cm.Type != "gauge" {
+			return fmt.Errorf("computed metric %s has type '%s', must be counter or gauge", cm.Name, cm.Type)
+		}
+		if cm.Expr.root == nil {
+			return fmt.Errorf("computed metric %s has no expr", cm.Name)
+		}
+
+		var first *Metric
+		for _, name := range cm.Expr.Vars() {
+			metric, ok := metrics[name]
+			if !ok {
+				return fmt.Errorf("computed metric %s uses unknown metric %s", cm.Name, name)
+			}
+			switch metric.Type {
+			case "counter", "gauge", "Float", "Double", "TimeTicks":
+			default:
+				return fmt.Errorf("computed metric %s uses metric %s of non-numeric type %s", cm.Name, name, metric.Type)
+			}
+			if first == nil {
+				first = metric
+				continue
+			}
+			if !sameIndexes(first, metric) {
+				return fmt.Errorf("computed metric %s uses metrics %s and %s with different indexes", cm.Name, first.Name, name)
+			}
+		}
+		if first == nil {
+			return fmt.Errorf("computed metric %s uses no metrics", cm.Name)
+		}
+	}
+	return nil
+}
+
+func sameIndexes(a, b *Metric) bool {
+	if len(a.Indexes) != len(b.Indexes) {
+		return false
+	}
+	for i := range a.Indexes {
+		if a.Indexes[i].Labelname != b.Indexes[i].Labelname || a.Indexes[i].Type != b.Indexes[i].Type {
+			return false
+		}
+	}
+	return true
+}
+
+// Check that the static and scalar labels of a module are valid and don't
+// collide with each other or with the labels of any metric.
+func (c *Module) checkModuleLabels() error {
+	moduleLabels := map[string]struct{}{}
+	addLabel := func(name string) error {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid module label name '%s'", name)
+		}
+		if _, ok := moduleLabels[name]; ok {
+			return fmt.Errorf("duplicate module label '%s'", name)
+		}
+		moduleLabels[name] = struct{}{}
+		return nil
+	}
+	for name := range c.StaticLabels {
+		if err := addLabel(name); err != nil {
+			return err
+		}
+	}
+	for _, scalar := range c.ScalarLabels {
+		if scalar.Oid == "" {
+			return fmt.Errorf("scalar label %s has no oid", scalar.Labelname)
+		}
+		if err := addLabel(scalar.Labelname); err != nil {
+			return err
+		}
+	}
+	if len(moduleLabels) == 0 {
+		return nil
+	}
+
+	for _, metric := range c.Metrics {
+		labels := []string{}
+		switch metric.Type {
+		case "counter", "gauge", "Float", "Double", "DateAndTime", "TimeTicks", "TimeTicksAsTimestamp":
+		default:
+			// Other types use the metric name as a label.
+			labels = append(labels, metric.Name)
+		}
+		for _, index := range metric.Indexes {
+			labels = append(labels, index.Labelname)
+		}
+		for _, lookup := range metric.Lookups {
+			labels = append(labels, lookup.Labelname)
+		}
+		for _, label := range labels {
+			if _, ok := moduleLabels[label]; ok {
+				return fmt.Errorf("module label '%s' collides with a label of metric %s", label, metric.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Order lookups so that each runs after the lookups producing the labels it
+// uses, otherwise keeping their order. Lookups without labels delete their
+// label, so run after everything using or producing it.
+func sortLookups(lookups []*Lookup) ([]*Lookup, error) {
+	producers := map[string][]int{}
+	consumers := map[string][]int{}
+	for i, lookup := range lookups {
+		if len(lookup.Labels) == 0 {
+			continue
+		}
+		producers[lookup.Labelname] = append(producers[lookup.Labelname], i)
+		for _, label := range lookup.Labels {
+			consumers[label] = append(consumers[label], i)
+		}
+	}
+	deps := make([][]int, len(lookups))
+	for i, lookup := range lookups {
+		if len(lookup.Labels) == 0 {
+			deps[i] = append(append(deps[i], consumers[lookup.Labelname]...), producers[lookup.Labelname]...)
+			continue
+		}
+		for _, label := range lookup.Labels {
+			for _, p := range producers[label] {
+				// A lookup replacing a label uses the previous value.
+				if p != i {
+					deps[i] = append(deps[i], p)
+				}
+			}
+		}
+	}
+
+	sorted := make([]*Lookup, 0, len(lookups))
+	done := make([]bool, len(lookups))
+	for len(sorted) < len(lookups) {
+		progress := false
+		for i, lookup := range lookups {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, d := range deps[i] {
+				if !done[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, lookup)
+				done[i] = true
+				progress = true
+				// Restart, to keep the original order where possible.
+				break
+			}
+		}
+		if !progress {
+			cycle := []string{}
+			for i, lookup := range lookups {
+				if !done[i] {
+					cycle = append(cycle, lookup.Labelname)
+				}
+			}
+			return nil, fmt.Errorf("lookups of %s depend on each other in a cycle", strings.Join(cycle, ", "))
+		}
+	}
+	return sorted, nil
+}
+
+// EncodingAuto detects the character set of each value.
+const EncodingAuto = "auto"
+
+func checkEncoding(name string) error {
+	if name == "" || name == EncodingAuto {
+		return nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return fmt.Errorf("unsupported encoding '%s'", name)
+	}
+	return nil
+}
+
+// ByteFormat is a way of rendering a byte string as hex.
+type ByteFormat struct {
+	Prefix    string
+	Separator string
+	// Bytes per group between separators.
+	Group     int
+	Lowercase bool
+}
+
+var (
+	// MACFormats are the renderings of PhysAddress48 values.
+	MACFormats = map[string]ByteFormat{
+		"colon":        {Separator: ":", Group: 1},                  // 00:1A:2B:3C:4D:5E
+		"colon_lower":  {Separator: ":", Group: 1, Lowercase: true}, // 00:1a:2b:3c:4d:5e
+		"hyphen":       {Separator: "-", Group: 1},                  // 00-1A-2B-3C-4D-5E
+		"hyphen_lower": {Separator: "-", Group: 1, Lowercase: true}, // 00-1a-2b-3c-4d-5e
+		"dot":          {Separator: ".", Group: 2, Lowercase: true}, // 001a.2b3c.4d5e
+		"plain":        {},                                          // 001A2B3C4D5E
+		"plain_lower":  {Lowercase: true},                           // 001a2b3c4d5e
+	}
+	// HexFormats are the renderings of OctetString values.
+	HexFormats = map[string]ByteFormat{
+		"prefixed":       {Prefix: "0x"},                              // 0x001A2B
+		"prefixed_lower": {Prefix: "0x", Lowercase: true},             // 0x001a2b
+		"plain":          {},                                          // 001A2B
+		"plain_lower":    {Lowercase: true},                           // 001a2b
+		"colon":          {Separator: ":", Group: 1},                  // 00:1A:2B
+		"colon_lower":    {Separator: ":", Group: 1, Lowercase: true}, // 00:1a:2b
+		"hyphen":         {Separator: "-", Group: 1},                  // 00-1A-2B
+		"hyphen_lower":   {Separator: "-", Group: 1, Lowercase: true}, // 00-1a-2b
+	}
+)
+
+// Formats selects the renderings of MAC addresses and hex strings.
+// Empty means the default, colon and prefixed respectively.
+type Formats struct {
+	MACFormat string `yaml:"mac_format,omitempty"`
+	HexFormat string `yaml:"hex_format,omitempty"`
+}
+
+func (f Formats) check() error {
+	if _, ok := MACFormats[f.MACFormat]; f.MACFormat != "" && !ok {
+		return fmt.Errorf("unknown mac_format '%s'", f.MACFormat)
+	}
+	if _, ok := HexFormats[f.HexFormat]; f.HexFormat != "" && !ok {
+		return fmt.Errorf("unknown hex_format '%s'", f.HexFormat)
+	}
+	return nil
+}
+
+func (f Formats) inherit(parent Formats) Formats {
+	if f.MACFormat == "" {
+		f.MACFormat = parent.MACFormat
+	}
+	if f.HexFormat == "" {
+		f.HexFormat = parent.HexFormat
+	}
+	return f
 }
 
 // ConfigureSNMP sets the various version and auth settings.
@@ -193,21 +624,46 @@ type Metric struct {
 	EnumValues     map[int]string             `yaml:"enum_values,omitempty"`
 	Offset         float64                    `yaml:"offset,omitempty"`
 	Scale          float64                    `yaml:"scale,omitempty"`
+	DisplayHint    string                     `yaml:"display_hint,omitempty"`
+	Encoding       string                     `yaml:"encoding,omitempty"`
+	Formats        `yaml:",inline"`
+	// OID of the column with the low 32 bits of the value, the metric's
+	// own column having the high 32 bits.
+	Combine string `yaml:"combine,omitempty"`
+	// Base unit of the value, such as seconds or bytes, which the name ends with.
+	Unit string `yaml:"unit,omitempty"`
 }
 
 type Index struct {
-	Labelname  string         `yaml:"labelname"`
-	Type       string         `yaml:"type"`
-	FixedSize  int            `yaml:"fixed_size,omitempty"`
-	Implied    bool           `yaml:"implied,omitempty"`
-	EnumValues map[int]string `yaml:"enum_values,omitempty"`
+	Labelname   string         `yaml:"labelname"`
+	Type        string         `yaml:"type"`
+	FixedSize   int            `yaml:"fixed_size,omitempty"`
+	Implied     bool           `yaml:"implied,omitempty"`
+	EnumValues  map[int]string `yaml:"enum_values,omitempty"`
+	DisplayHint string         `yaml:"display_hint,omitempty"`
+	Formats     `yaml:",inline"`
 }
 
 type Lookup struct {
-	Labels    []string `yaml:"labels"`
-	Labelname string   `yaml:"labelname"`
-	Oid       string   `yaml:"oid,omitempty"`
-	Type      string   `yaml:"type,omitempty"`
+	Labels      []string `yaml:"labels"`
+	Labelname   string   `yaml:"labelname"`
+	Oid         string   `yaml:"oid,omitempty"`
+	Type        string   `yaml:"type,omitempty"`
+	DisplayHint string   `yaml:"display_hint,omitempty"`
+	Encoding    string   `yaml:"encoding,omitempty"`
+	Formats     `yaml:",inline"`
+	// Join via the value of another column, rather than the labels directly.
+	Via *LookupVia `yaml:"via,omitempty"`
+}
+
+// LookupVia is a column indexed by the labels of a lookup, whose value is
+// the index of the lookup.
+type LookupVia struct {
+	Oid  string `yaml:"oid"`
+	Type string `yaml:"type"`
+	// How the value is encoded in the index of the lookup.
+	FixedSize int  `yaml:"fixed_size,omitempty"`
+	Implied   bool `yaml:"implied,omitempty"`
 }
 
 // Secret is a string that must not be revealed on marshaling.
@@ -0,0 +1,261 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Expr is an arithmetic expression over the values of metrics, such as
+// "hrStorageSize - hrStorageUsed" or "ifHCInOctets * 8". It supports
+// numbers, metric names, parentheses, unary minus and the binary
+// operators +, -, *, /, % and ^ (power), with the usual precedence.
+type Expr struct {
+	src  string
+	root exprNode
+	vars []string
+}
+
+type exprNode interface {
+	eval(vars map[string]float64) float64
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]float64) float64 { return float64(n) }
+
+type varNode string
+
+func (n varNode) eval(vars map[string]float64) float64 { return vars[string(n)] }
+
+type negNode struct{ x exprNode }
+
+func (n negNode) eval(vars map[string]float64) float64 { return -n.x.eval(vars) }
+
+type binaryNode struct {
+	op   byte
+	x, y exprNode
+}
+
+func (n binaryNode) eval(vars map[string]float64) float64 {
+	x, y := n.x.eval(vars), n.y.eval(vars)
+	switch n.op {
+	case '+':
+		return x + y
+	case '-':
+		return x - y
+	case '*':
+		return x * y
+	case '/':
+		return x / y
+	case '%':
+		return math.Mod(x, y)
+	default:
+		return math.Pow(x, y)
+	}
+}
+
+// ParseExpr parses an expression.
+func ParseExpr(s string) (*Expr, error) {
+	p := &exprParser{src: s}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %s", s, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("invalid expression '%s': unexpected '%c' at position %d", s, p.src[p.pos], p.pos)
+	}
+	return &Expr{src: s, root: root, vars: p.vars}, nil
+}
+
+// Vars returns the metric names used by the expression, in order of
+// first use.
+func (e *Expr) Vars() []string {
+	return e.vars
+}
+
+// Eval evaluates the expression with the given metric values, all of
+// which must be set.
+func (e *Expr) Eval(vars map[string]float64) float64 {
+	return e.root.eval(vars)
+}
+
+func (e Expr) String() string {
+	return e.src
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (e *Expr) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	expr, err := ParseExpr(s)
+	if err != nil {
+		return err
+	}
+	*e = *expr
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (e Expr) MarshalYAML() (interface{}, error) {
+	return e.src, nil
+}
+
+// A recursive descent parser, one function per precedence level.
+type exprParser struct {
+	src  string
+	pos  int
+	vars []string
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// Consume the next character if it is one of ops.
+func (p *exprParser) accept(ops string) (byte, bool) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0, false
+	}
+	for i := 0; i < len(ops); i++ {
+		if p.src[p.pos] == ops[i] {
+			p.pos++
+			return ops[i], true
+		}
+	}
+	return 0, false
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	x, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+-")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryNode{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*/%")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryNode{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.accept("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{x: x}, nil
+	}
+	return p.parsePower()
+}
+
+// Power is right associative and binds tighter than unary minus on its
+// left, so -2^2 is -4.
+func (p *exprParser) parsePower() (exprNode, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("^"); ok {
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: '^', x: x, y: y}, nil
+	}
+	return x, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if _, ok := p.accept("("); ok {
+		x, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		return x, nil
+	}
+	p.skipSpace()
+	start := p.pos
+	switch {
+	case p.pos >= len(p.src):
+		return nil, fmt.Errorf("unexpected end")
+	case isDigit(p.src[p.pos]) || p.src[p.pos] == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", p.src[start:p.pos])
+		}
+		return numberNode(v), nil
+	case isIdentStart(p.src[p.pos]):
+		for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		seen := false
+		for _, v := range p.vars {
+			seen = seen || v == name
+		}
+		if !seen {
+			p.vars = append(p.vars, name)
+		}
+		return varNode(name), nil
+	default:
+		return nil, fmt.Errorf("unexpected '%c' at position %d", p.src[p.pos], p.pos)
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Relabel actions, as in Prometheus.
+const (
+	RelabelReplace   = "replace"
+	RelabelKeep      = "keep"
+	RelabelDrop      = "drop"
+	RelabelHashMod   = "hashmod"
+	RelabelLabelMap  = "labelmap"
+	RelabelLabelDrop = "labeldrop"
+)
+
+var (
+	DefaultRelabelConfig = RelabelConfig{
+		Separator:   ";",
+		Replacement: "$1",
+		Action:      RelabelReplace,
+	}
+	defaultRelabelRegex = regexp.MustCompile("^(?:(.*))$")
+)
+
+// RelabelConfig is a Prometheus style relabeling rule, applied to the
+// samples of a module. The metric name is the __name__ label.
+type RelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels,flow,omitempty"`
+	Separator    string   `yaml:"separator,omitempty"`
+	Regex        Regexp   `yaml:"regex,omitempty"`
+	Modulus      uint64   `yaml:"modulus,omitempty"`
+	TargetLabel  string   `yaml:"target_label,omitempty"`
+	Replacement  string   `yaml:"replacement,omitempty"`
+	Action       string   `yaml:"action,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RelabelConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultRelabelConfig
+	type plain RelabelConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Regex.Regexp == nil {
+		c.Regex.Regexp = defaultRelabelRegex
+	}
+
+	switch c.Action {
+	case RelabelReplace, RelabelHashMod:
+		if c.TargetLabel == "" {
+			return fmt.Errorf("relabel action %s requires a target_label", c.Action)
+		}
+		if c.Action == RelabelHashMod && c.Modulus == 0 {
+			return fmt.Errorf("relabel action %s requires a non-zero modulus", c.Action)
+		}
+	case RelabelKeep, RelabelDrop:
+		if len(c.SourceLabels) == 0 {
+			return fmt.Errorf("relabel action %s requires source_labels", c.Action)
+		}
+	case RelabelLabelMap, RelabelLabelDrop:
+		if len(c.SourceLabels) != 0 {
+			return fmt.Errorf("relabel action %s does not use source_labels", c.Action)
+		}
+	default:
+		return fmt.Errorf("unknown relabel action '%s'", c.Action)
+	}
+	return nil
+}
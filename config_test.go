@@ -16,8 +16,11 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
+
+	"github.com/prometheus/snmp_exporter/config"
 )
 
 func TestHideConfigSecrets(t *testing.T) {
@@ -67,3 +70,265 @@ func TestLoadMultipleConfigs(t *testing.T) {
 		t.Errorf("Error marshaling config: %v", err)
 	}
 }
+
+func TestLoadConfigWithEncoding(t *testing.T) {
+	sc := &SafeConfig{}
+	err := sc.ReloadConfig([]string{"testdata/snmp-with-encoding.yml"})
+	if err != nil {
+		t.Fatalf("Error loading config %v: %v", "testdata/snmp-with-encoding.yml", err)
+	}
+	sc.RLock()
+	defer sc.RUnlock()
+	metrics := sc.C.Modules["default"].Metrics
+	// Metrics inherit the module's encoding, and lookups the metric's.
+	if metrics[0].Encoding != "GBK" {
+		t.Errorf("Expected sysLocation to inherit encoding GBK, got %q", metrics[0].Encoding)
+	}
+	if metrics[1].Encoding != "auto" || metrics[1].Lookups[0].Encoding != "auto" {
+		t.Errorf("Expected ifAlias and its lookup to use encoding auto, got %q and %q", metrics[1].Encoding, metrics[1].Lookups[0].Encoding)
+	}
+
+	err = yaml.UnmarshalStrict([]byte("modules:\n  default:\n    encoding: no-such-charset\n"), &config.Config{})
+	if err == nil {
+		t.Errorf("Expected error for unsupported encoding")
+	}
+}
+
+func TestLoadConfigWithFormats(t *testing.T) {
+	sc := &SafeConfig{}
+	err := sc.ReloadConfig([]string{"testdata/snmp-with-formats.yml"})
+	if err != nil {
+		t.Fatalf("Error loading config %v: %v", "testdata/snmp-with-formats.yml", err)
+	}
+	sc.RLock()
+	defer sc.RUnlock()
+	metric := sc.C.Modules["default"].Metrics[0]
+	// Indexes inherit the formats of the metric, which inherits those of the module.
+	expected := []config.Formats{
+		{MACFormat: "dot", HexFormat: "plain_lower"},
+		{MACFormat: "hyphen_lower", HexFormat: "plain_lower"},
+	}
+	for i, index := range metric.Indexes {
+		if index.Formats != expected[i] {
+			t.Errorf("Expected index %s to have formats %v, got %v", index.Labelname, expected[i], index.Formats)
+		}
+	}
+
+	err = yaml.UnmarshalStrict([]byte("modules:\n  default:\n    mac_format: no-such-format\n"), &config.Config{})
+	if err == nil {
+		t.Errorf("Expected error for unknown mac_format")
+	}
+}
+
+func TestLoadConfigLookupOrder(t *testing.T) {
+	cases := []struct {
+		lookups string
+		order   []string
+		err     bool
+	}{
+		{
+			// Already in order.
+			lookups: `
+      - {labels: [a], labelname: b, oid: 1.2}
+      - {labels: [b], labelname: c, oid: 1.3}
+      - {labels: [], labelname: a}`,
+			order: []string{"b", "c", "a"},
+		},
+		{
+			// Dependencies come first, deletions after all uses of the label.
+			lookups: `
+      - {labels: [], labelname: a}
+      - {labels: [c], labelname: d, oid: 1.4}
+      - {labels: [b], labelname: c, oid: 1.3}
+      - {labels: [a], labelname: b, oid: 1.2}
+      - {labels: [a], labelname: a, oid: 1.5}`,
+			order: []string{"a", "b", "a", "c", "d"},
+		},
+		{
+			lookups: `
+      - {labels: [c], labelname: b, oid: 1.2}
+      - {labels: [b], labelname: c, oid: 1.3}`,
+			err: true,
+		},
+	}
+	for _, c := range cases {
+		content := "modules:\n  default:\n    metrics:\n    - name: m\n      oid: 1.1\n      type: gauge\n      lookups:" + c.lookups + "\n"
+		cfg := &config.Config{}
+		err := yaml.UnmarshalStrict([]byte(content), cfg)
+		if c.err {
+			if err == nil {
+				t.Errorf("Expected error for lookups %s", c.lookups)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for lookups %s: %v", c.lookups, err)
+			continue
+		}
+		order := []string{}
+		for _, l := range cfg.Modules["default"].Metrics[0].Lookups {
+			order = append(order, l.Labelname)
+		}
+		if strings.Join(order, ",") != strings.Join(c.order, ",") {
+			t.Errorf("Expected lookups ordered as %v, got %v", c.order, order)
+		}
+	}
+}
+
+func TestLoadConfigWithRelabeling(t *testing.T) {
+	cases := []struct {
+		relabel string
+		err     bool
+	}{
+		{relabel: `[{source_labels: [ifDescr], regex: "lo", action: drop}]`},
+		{relabel: `[{source_labels: [ifDescr], target_label: port}]`},
+		{relabel: `[{regex: "if(.*)", action: labelmap}]`},
+		{relabel: `[{source_labels: [ifDescr], action: replace}]`, err: true},
+		{relabel: `[{source_labels: [ifDescr], target_label: shard, action: hashmod}]`, err: true},
+		{relabel: `[{action: keep}]`, err: true},
+		{relabel: `[{source_labels: [ifDescr], action: labeldrop}]`, err: true},
+		{relabel: `[{source_labels: [ifDescr], action: no-such-action}]`, err: true},
+		{relabel: `[{source_labels: [ifDescr], regex: "(", action: drop}]`, err: true},
+	}
+	for _, c := range cases {
+		cfg := "modules:\n  default:\n    metric_relabel_configs: " + c.relabel + "\n"
+		err := yaml.UnmarshalStrict([]byte(cfg), &config.Config{})
+		if c.err && err == nil {
+			t.Errorf("Expected error for relabel config %s", c.relabel)
+		}
+		if !c.err && err != nil {
+			t.Errorf("Unexpected error for relabel config %s: %s", c.relabel, err)
+		}
+	}
+}
+
+func TestLoadConfigWithModuleLabels(t *testing.T) {
+	cases := []struct {
+		module string
+		err    bool
+	}{
+		{module: `{static_labels: {vendor: cisco}, scalar_labels: [{labelname: sysName, oid: 1.3.6.1.2.1.1.5.0, type: DisplayString}]}`},
+		{module: `{static_labels: {vendor: cisco}, metrics: [{name: ifInOctets, oid: 1.2, type: counter, indexes: [{labelname: ifIndex, type: gauge}]}]}`},
+		// Numeric metrics don't use their name as a label.
+		{module: `{static_labels: {ifInOctets: x}, metrics: [{name: ifInOctets, oid: 1.2, type: counter}]}`},
+		{module: `{static_labels: {ifIndex: x}, metrics: [{name: ifInOctets, oid: 1.2, type: counter, indexes: [{labelname: ifIndex, type: gauge}]}]}`, err: true},
+		{module: `{static_labels: {ifDescr: x}, metrics: [{name: ifDescr, oid: 1.2, type: DisplayString}]}`, err: true},
+		{module: `{scalar_labels: [{labelname: ifName, oid: 1.1.0, type: DisplayString}], metrics: [{name: ifInOctets, oid: 1.2, type: counter, lookups: [{labels: [ifIndex], labelname: ifName, oid: 1.3, type: DisplayString}]}]}`, err: true},
+		{module: `{static_labels: {sysName: x}, scalar_labels: [{labelname: sysName, oid: 1.1.0, type: DisplayString}]}`, err: true},
+		{module: `{static_labels: {"0bad": x}}`, err: true},
+		{module: `{scalar_labels: [{labelname: sysName, type: DisplayString}]}`, err: true},
+	}
+	for _, c := range cases {
+		cfg := "modules:\n  default: " + c.module + "\n"
+		err := yaml.UnmarshalStrict([]byte(cfg), &config.Config{})
+		if c.err && err == nil {
+			t.Errorf("Expected error for module %s", c.module)
+		}
+		if !c.err && err != nil {
+			t.Errorf("Unexpected error for module %s: %s", c.module, err)
+		}
+	}
+}
+
+func TestLoadConfigWithComputedMetrics(t *testing.T) {
+	metrics := `[{name: size, oid: 1.5, type: gauge, indexes: [{labelname: idx, type: gauge}]},
+	  {name: used, oid: 1.6, type: gauge, indexes: [{labelname: idx, type: gauge}]},
+	  {name: descr, oid: 1.7, type: DisplayString, indexes: [{labelname: idx, type: gauge}]},
+	  {name: scalar, oid: 1.8, type: gauge}]`
+	cases := []struct {
+		computed string
+		err      bool
+	}{
+		{computed: `[{name: free, type: gauge, expr: "size - used"}]`},
+		{computed: `[{name: used_percent, type: gauge, help: Used, expr: "used / size * 100"}]`},
+		{computed: `[{name: free, type: gauge, expr: "size -"}]`, err: true},
+		{computed: `[{name: free, type: gauge, expr: "size - missing"}]`, err: true},
+		{computed: `[{name: free, type: gauge, expr: "size - descr"}]`, err: true},
+		{computed: `[{name: free, type: gauge, expr: "size - scalar"}]`, err: true},
+		{computed: `[{name: free, type: gauge, expr: "42"}]`, err: true},
+		{computed: `[{name: free, type: gauge}]`, err: true},
+		{computed: `[{name: free, type: DisplayString, expr: "size - used"}]`, err: true},
+		{computed: `[{name: size, type: gauge, expr: "size - used"}]`, err: true},
+		{computed: `[{name: free, type: gauge, expr: "size"}, {name: free, type: gauge, expr: "used"}]`, err: true},
+	}
+	for _, c := range cases {
+		cfg := "modules:\n  default:\n    metrics: " + metrics + "\n    computed_metrics: " + c.computed + "\n"
+		err := yaml.UnmarshalStrict([]byte(cfg), &config.Config{})
+		if c.err && err == nil {
+			t.Errorf("Expected error for computed metrics %s", c.computed)
+		}
+		if !c.err && err != nil {
+			t.Errorf("Unexpected error for computed metrics %s: %s", c.computed, err)
+		}
+	}
+}
+
+func TestLoadConfigWithCombine(t *testing.T) {
+	cases := []struct {
+		metric string
+		err    bool
+	}{
+		{metric: `{name: fooOctets, oid: 1.1.2, type: counter, combine: 1.1.3}`},
+		{metric: `{name: fooOctets, oid: 1.1.2, type: gauge, combine: 1.1.3}`},
+		{metric: `{name: fooOctets, oid: 1.1.2, type: DisplayString, combine: 1.1.3}`, err: true},
+	}
+	for _, c := range cases {
+		cfg := "modules:\n  default:\n    metrics: [" + c.metric + "]\n"
+		err := yaml.UnmarshalStrict([]byte(cfg), &config.Config{})
+		if c.err && err == nil {
+			t.Errorf("Expected error for metric %s", c.metric)
+		}
+		if !c.err && err != nil {
+			t.Errorf("Unexpected error for metric %s: %s", c.metric, err)
+		}
+	}
+}
+
+func TestLoadConfigWithExtendCounters(t *testing.T) {
+	cfg := &config.Config{}
+	err := yaml.UnmarshalStrict([]byte("modules:\n  default:\n    extend_counters: {}\n  engine:\n    extend_counters: {uptime_oid: 1.3.6.1.6.3.10.2.1.3.0}\n"), cfg)
+	if err != nil {
+		t.Fatalf("Error loading config: %s", err)
+	}
+	if got := cfg.Modules["default"].ExtendCounters.UptimeOid; got != "1.3.6.1.2.1.1.3.0" {
+		t.Errorf("Expected sysUpTime.0 as the default uptime, got %s", got)
+	}
+	if got := cfg.Modules["engine"].ExtendCounters.UptimeOid; got != "1.3.6.1.6.3.10.2.1.3.0" {
+		t.Errorf("Expected snmpEngineTime.0 as the uptime, got %s", got)
+	}
+}
+
+func TestLoadConfigWithTimestamps(t *testing.T) {
+	cases := []struct {
+		timestamps string
+		expected   config.Timestamps
+		err        bool
+	}{
+		{timestamps: `{}`, expected: config.Timestamps{Source: "received", MaxAge: 5 * time.Minute}},
+		{timestamps: `{max_age: 30s}`, expected: config.Timestamps{Source: "received", MaxAge: 30 * time.Second}},
+		{
+			timestamps: `{source: device_clock, clock_oid: 1.3.6.1.2.1.25.1.2.0}`,
+			expected:   config.Timestamps{Source: "device_clock", ClockOid: "1.3.6.1.2.1.25.1.2.0", MaxAge: 5 * time.Minute},
+		},
+		{timestamps: `{source: device_clock}`, err: true},
+		{timestamps: `{source: scrape}`, err: true},
+		{timestamps: `{max_age: 0s}`, err: true},
+	}
+	for _, c := range cases {
+		cfg := &config.Config{}
+		err := yaml.UnmarshalStrict([]byte("modules:\n  default:\n    timestamps: "+c.timestamps+"\n"), cfg)
+		if c.err {
+			if err == nil {
+				t.Errorf("Expected error for timestamps %s", c.timestamps)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for timestamps %s: %s", c.timestamps, err)
+			continue
+		}
+		if got := *cfg.Modules["default"].Timestamps; got != c.expected {
+			t.Errorf("Timestamps %s: got %+v, want %+v", c.timestamps, got, c.expected)
+		}
+	}
+}
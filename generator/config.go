@@ -15,8 +15,11 @@ package main
 
 import (
 	"fmt"
-	"github.com/prometheus/snmp_exporter/config"
 	"strconv"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/snmp_exporter/config"
 )
 
 // The generator config.
@@ -32,6 +35,14 @@ type MetricOverrides struct {
 	Offset         float64                           `yaml:"offset,omitempty"`
 	Scale          float64                           `yaml:"scale,omitempty"`
 	Type           string                            `yaml:"type,omitempty"`
+	Name           string                            `yaml:"name,omitempty"`
+	Prefix         string                            `yaml:"prefix,omitempty"`
+	Help           string                            `yaml:"help,omitempty"`
+	Encoding       string                            `yaml:"encoding,omitempty"`
+	config.Formats `yaml:",inline"`
+	// Column with the low 32 bits of the value, making this metric a
+	// counter of both. The low column is not output separately.
+	Combine string `yaml:"combine,omitempty"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -45,6 +56,12 @@ func (c *MetricOverrides) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.Type != "" && (!ok || typ != c.Type) {
 		return fmt.Errorf("invalid metric type override '%s'", c.Type)
 	}
+	if c.Name != "" && !model.IsValidMetricName(model.LabelValue(c.Name)) {
+		return fmt.Errorf("invalid metric name override '%s'", c.Name)
+	}
+	if c.Prefix != "" && !model.IsValidMetricName(model.LabelValue(c.Prefix)) {
+		return fmt.Errorf("invalid metric prefix override '%s'", c.Prefix)
+	}
 
 	return nil
 }
@@ -55,6 +72,30 @@ type ModuleConfig struct {
 	WalkParams config.WalkParams          `yaml:",inline"`
 	Overrides  map[string]MetricOverrides `yaml:"overrides"`
 	Filters    config.Filters             `yaml:"filters,omitempty"`
+	// Prepended to the names of all metrics of the module.
+	MetricPrefix string `yaml:"metric_prefix,omitempty"`
+	// Convert values to base units based on the UNITS of objects.
+	ConvertUnits bool `yaml:"convert_units,omitempty"`
+	// Character set of string values, or auto to detect it.
+	Encoding string `yaml:"encoding,omitempty"`
+	// Rendering of MAC addresses and hex strings.
+	config.Formats `yaml:",inline"`
+	// Relabeling done by the exporter, copied to the module.
+	MetricRelabelConfigs []*config.RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
+	// Labels added to all samples of the module.
+	StaticLabels map[string]string `yaml:"static_labels,omitempty"`
+	// Scalar objects whose values are added to all samples, by label name.
+	ScalarLabels map[string]string `yaml:"scalar_labels,omitempty"`
+	// Metrics computed by the exporter, copied to the module.
+	ComputedMetrics []*config.ComputedMetric `yaml:"computed_metrics,omitempty"`
+	// Extend Counter32 metrics to 64 bits in the exporter. The uptime can be
+	// given as the name of a scalar, such as sysUpTime.
+	ExtendCounters *config.ExtendCounters `yaml:"extend_counters,omitempty"`
+	// Expose the ASN.1 types of values as snmp_metric_info.
+	MetricInfo bool `yaml:"metric_info,omitempty"`
+	// Explicit timestamps of samples. The clock can be given as the name of
+	// a scalar, such as hrSystemDate.
+	Timestamps *config.Timestamps `yaml:"timestamps,omitempty"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -64,6 +105,10 @@ func (c *ModuleConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	if c.MetricPrefix != "" && !model.IsValidMetricName(model.LabelValue(c.MetricPrefix)) {
+		return fmt.Errorf("invalid metric_prefix '%s'", c.MetricPrefix)
+	}
+
 	// Ensure indices in static filters are integer for input validation.
 	for _, filter := range c.Filters.Static {
 		for _, index := range filter.Indices {
@@ -81,4 +126,6 @@ type Lookup struct {
 	SourceIndexes     []string `yaml:"source_indexes"`
 	Lookup            string   `yaml:"lookup"`
 	DropSourceIndexes bool     `yaml:"drop_source_indexes,omitempty"`
+	// A column indexed by the source indexes, whose value indexes the lookup.
+	Via string `yaml:"via,omitempty"`
 }
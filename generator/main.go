@@ -14,10 +14,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"runtime"
+	"sort"
+	"sync"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
@@ -30,7 +33,7 @@ import (
 )
 
 // Generate a snmp_exporter config and write it out.
-func generateConfig(nodes *Node, nameToNode map[string]*Node, logger log.Logger) error {
+func generateConfig(nodes *Node, nameToNode map[string]*Node, parseErrors []*ParseError, logger log.Logger) error {
 	outputPath, err := filepath.Abs(*outputPath)
 	if err != nil {
 		return fmt.Errorf("unable to determine absolute path for output")
@@ -49,23 +52,42 @@ func generateConfig(nodes *Node, nameToNode map[string]*Node, logger log.Logger)
 	outputConfig := config.Config{}
 	outputConfig.Auths = cfg.Auths
 	outputConfig.Modules = make(map[string]*config.Module, len(cfg.Modules))
-	for name, m := range cfg.Modules {
-		level.Info(logger).Log("msg", "Generating config for module", "module", name)
-		// Give each module a copy of the tree so that it can be modified.
-		mNodes := nodes.Copy()
-		// Build the map with new pointers.
-		mNameToNode := map[string]*Node{}
-		walkNode(mNodes, func(n *Node) {
-			mNameToNode[n.Oid] = n
-			mNameToNode[n.Label] = n
-		})
-		out, err := generateConfigModule(m, mNodes, mNameToNode, logger)
-		if err != nil {
-			return err
+
+	// Generate the modules concurrently, they share the tree read-only.
+	names := make([]string, 0, len(cfg.Modules))
+	for name := range cfg.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	outs := make([]*config.Module, len(names))
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			level.Info(logger).Log("msg", "Generating config for module", "module", name)
+			outs[i], errs[i] = generateConfigModule(cfg.Modules[name], nodes, nameToNode, logger)
+		}(i, name)
+	}
+	wg.Wait()
+
+	walkedErrors := 0
+	for i, name := range names {
+		if errs[i] != nil {
+			return errs[i]
 		}
+		out := outs[i]
+		out.WalkParams = cfg.Modules[name].WalkParams
 		outputConfig.Modules[name] = out
-		outputConfig.Modules[name].WalkParams = m.WalkParams
-		level.Info(logger).Log("msg", "Generated metrics", "module", name, "metrics", len(outputConfig.Modules[name].Metrics))
+		level.Info(logger).Log("msg", "Generated metrics", "module", name, "metrics", len(out.Metrics))
+		for _, e := range walkedParseErrors(out, parseErrors) {
+			level.Warn(logger).Log("msg", "MIB parse error affects walked objects", "module", name, "err", e)
+			walkedErrors++
+		}
 	}
 
 	config.DoNotHideSecrets = true
@@ -91,18 +113,50 @@ func generateConfig(nodes *Node, nameToNode map[string]*Node, logger log.Logger)
 		return fmt.Errorf("error writing to output file: %s", err)
 	}
 	level.Info(logger).Log("msg", "Config written", "file", outputPath)
+	if *failOnWalkedParseErrors && walkedErrors != 0 {
+		return fmt.Errorf("%d MIB parse error(s) affect walked objects", walkedErrors)
+	}
 	return nil
 }
 
+// Find the parse errors that affect the objects walked, fetched or
+// looked up by a generated module.
+func walkedParseErrors(m *config.Module, parseErrors []*ParseError) []*ParseError {
+	oids := append([]string{}, m.Walk...)
+	oids = append(oids, m.Get...)
+	for _, metric := range m.Metrics {
+		oids = append(oids, metric.Oid)
+		for _, lookup := range metric.Lookups {
+			oids = append(oids, lookup.Oid)
+		}
+	}
+	affected := []*ParseError{}
+	for _, e := range parseErrors {
+		if e.Severity != severityError {
+			continue
+		}
+		for _, oid := range oids {
+			if e.Affects(oid) {
+				affected = append(affected, e)
+				break
+			}
+		}
+	}
+	return affected
+}
+
 var (
-	failOnParseErrors  = kingpin.Flag("fail-on-parse-errors", "Exit with a non-zero status if there are MIB parsing errors").Default("false").Bool()
-	snmpMIBOpts        = kingpin.Flag("snmp.mibopts", "Toggle various defaults controlling MIB parsing, see snmpwalk --help").String()
-	generateCommand    = kingpin.Command("generate", "Generate snmp.yml from generator.yml")
-	userMibsDir        = generateCommand.Flag("mibs-dir", "Paths to mibs directory").Default("").Short('m').Strings()
-	generatorYmlPath   = generateCommand.Flag("generator-path", "Path to the input generator.yml file").Default("generator.yml").Short('g').String()
-	outputPath         = generateCommand.Flag("output-path", "Path to write the snmp_exporter's config file").Default("snmp.yml").Short('o').String()
-	parseErrorsCommand = kingpin.Command("parse_errors", "Debug: Print the parse errors output by NetSNMP")
-	dumpCommand        = kingpin.Command("dump", "Debug: Dump the parsed and prepared MIBs")
+	failOnParseErrors       = kingpin.Flag("fail-on-parse-errors", "Exit with a non-zero status if there are MIB parsing errors").Default("false").Bool()
+	snmpMIBOpts             = kingpin.Flag("snmp.mibopts", "Toggle various defaults controlling MIB parsing, see README.md").String()
+	mibCacheFile            = kingpin.Flag("mib-cache-file", "File to cache the parsed MIBs in between runs, disabled if empty").Default("").String()
+	generateCommand         = kingpin.Command("generate", "Generate snmp.yml from generator.yml")
+	userMibsDir             = generateCommand.Flag("mibs-dir", "Paths to mibs directory").Default("").Short('m').Strings()
+	generatorYmlPath        = generateCommand.Flag("generator-path", "Path to the input generator.yml file").Default("generator.yml").Short('g').String()
+	outputPath              = generateCommand.Flag("output-path", "Path to write the snmp_exporter's config file").Default("snmp.yml").Short('o').String()
+	failOnWalkedParseErrors = generateCommand.Flag("fail-on-walked-parse-errors", "Exit with a non-zero status if MIB parsing errors affect objects used by a module").Default("false").Bool()
+	parseErrorsCommand      = kingpin.Command("parse_errors", "Debug: Print the MIB parse errors")
+	parseErrorsFormat       = parseErrorsCommand.Flag("format", "Output format of the parse errors").Default("text").Enum("text", "json")
+	dumpCommand             = kingpin.Command("dump", "Debug: Dump the parsed and prepared MIBs")
 )
 
 func main() {
@@ -112,30 +166,40 @@ func main() {
 	command := kingpin.Parse()
 	logger := promlog.New(promlogConfig)
 
-	parseOutput, err := initSNMP(logger)
+	nodes, parseErrors, err := loadMIBs(getMibsDir(*userMibsDir), *snmpMIBOpts, *mibCacheFile, logger)
 	if err != nil {
-		level.Error(logger).Log("msg", "Error initializing netsnmp", "err", err)
+		level.Error(logger).Log("msg", "Error loading MIBs", "err", err)
 		os.Exit(1)
 	}
-
-	parseOutput = strings.TrimSpace(parseOutput)
-	parseErrors := len(parseOutput) != 0
-	if parseErrors {
-		level.Warn(logger).Log("msg", "NetSNMP reported parse error(s)", "errors", len(strings.Split(parseOutput, "\n")))
+	if len(parseErrors) != 0 {
+		level.Warn(logger).Log("msg", "MIB parse error(s)", "errors", len(parseErrors))
 	}
 
-	nodes := getMIBTree()
 	nameToNode := prepareTree(nodes, logger)
 
 	switch command {
 	case generateCommand.FullCommand():
-		err := generateConfig(nodes, nameToNode, logger)
+		err := generateConfig(nodes, nameToNode, parseErrors, logger)
 		if err != nil {
-			level.Error(logger).Log("msg", "Error generating config netsnmp", "err", err)
+			level.Error(logger).Log("msg", "Error generating config", "err", err)
 			os.Exit(1)
 		}
 	case parseErrorsCommand.FullCommand():
-		fmt.Println(parseOutput)
+		if *parseErrorsFormat == "json" {
+			if parseErrors == nil {
+				parseErrors = []*ParseError{}
+			}
+			out, err := json.MarshalIndent(parseErrors, "", "  ")
+			if err != nil {
+				level.Error(logger).Log("msg", "Error marshaling parse errors", "err", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(out))
+			break
+		}
+		for _, e := range parseErrors {
+			fmt.Println(e)
+		}
 	case dumpCommand.FullCommand():
 		walkNode(nodes, func(n *Node) {
 			t := n.Type
@@ -150,7 +214,11 @@ func main() {
 				n.Oid, n.Label, t, n.TextualConvention, n.Hint, n.Indexes, implied, n.EnumValues, n.Description)
 		})
 	}
-	if *failOnParseErrors && parseErrors {
-		os.Exit(1)
+	if *failOnParseErrors {
+		for _, e := range parseErrors {
+			if e.Severity == severityError {
+				os.Exit(1)
+			}
+		}
 	}
 }
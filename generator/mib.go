@@ -0,0 +1,710 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// One entry in the tree of the MIB.
+type Node struct {
+	Oid               string
+	subid             int64
+	Label             string
+	Augments          string
+	Children          []*Node
+	Description       string
+	Type              string
+	Hint              string
+	TextualConvention string
+	FixedSize         int
+	Units             string
+	Access            string
+	EnumValues        map[int]string
+
+	Indexes      []string
+	ImpliedIndex bool
+
+	// The MIB module the node is defined in.
+	Module string
+	// The objects of a group, or the groups of a compliance statement.
+	Members []string
+}
+
+// Severities of problems found in MIBs.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// ParseError is a problem found while loading a MIB file.
+type ParseError struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Severity string `json:"severity"`
+	// The object affected by the problem, and its OID if it is known.
+	// Syntax errors have the OID of the module if it has an identity.
+	Object string `json:"object,omitempty"`
+	Oid    string `json:"oid,omitempty"`
+	// The module which could not be found, if the problem was caused by
+	// a missing import.
+	MissingImport string `json:"missing_import,omitempty"`
+	Message       string `json:"message"`
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d", e.Line)
+	}
+	fmt.Fprintf(&b, ": %s", e.Severity)
+	if e.Module != "" {
+		fmt.Fprintf(&b, ": %s", e.Module)
+	}
+	fmt.Fprintf(&b, ": %s", e.Message)
+	return b.String()
+}
+
+// Affects returns whether the problem is within, or contains, the subtree at oid.
+// A problem with an object whose OID is unknown could be anywhere, so affects
+// every subtree. Problems with the imports of a module only matter through the
+// objects using them, which are reported separately.
+func (e *ParseError) Affects(oid string) bool {
+	if e.Oid == "" {
+		return e.Object != ""
+	}
+	return strings.HasPrefix(oid+".", e.Oid+".") || strings.HasPrefix(e.Oid+".", oid+".")
+}
+
+// OIDs defined by the SMI itself, so that MIBs can be loaded without
+// the SMI modules being present.
+var smiWellKnownOids = map[string]string{
+	"ccitt":           "0",
+	"iso":             "1",
+	"joint-iso-ccitt": "2",
+	"org":             "1.3",
+	"dod":             "1.3.6",
+	"internet":        "1.3.6.1",
+	"directory":       "1.3.6.1.1",
+	"mgmt":            "1.3.6.1.2",
+	"mib-2":           "1.3.6.1.2.1",
+	"transmission":    "1.3.6.1.2.1.10",
+	"experimental":    "1.3.6.1.3",
+	"private":         "1.3.6.1.4",
+	"enterprises":     "1.3.6.1.4.1",
+	"security":        "1.3.6.1.5",
+	"snmpV2":          "1.3.6.1.6",
+	"snmpDomains":     "1.3.6.1.6.1",
+	"snmpProxys":      "1.3.6.1.6.2",
+	"snmpModules":     "1.3.6.1.6.3",
+}
+
+// Modules whose macros and base types are built in.
+var smiModules = map[string]bool{
+	"SNMPv2-SMI":  true,
+	"SNMPv2-TC":   true,
+	"SNMPv2-CONF": true,
+	"RFC1065-SMI": true,
+	"RFC1155-SMI": true,
+	"RFC-1212":    true,
+	"RFC-1215":    true,
+}
+
+var smiMacros = map[string]bool{
+	"OBJECT-TYPE":        true,
+	"TRAP-TYPE":          true,
+	"TEXTUAL-CONVENTION": true,
+	"MODULE-IDENTITY":    true,
+	"OBJECT-IDENTITY":    true,
+	"NOTIFICATION-TYPE":  true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"AGENT-CAPABILITIES": true,
+}
+
+// getMibsDir returns the directories to load MIBs from. If the user didn't pass any,
+// MIBDIRS is used as NetSNMP does, falling back to the NetSNMP default directories.
+func getMibsDir(paths []string) []string {
+	if len(paths) == 0 || (len(paths) == 1 && paths[0] == "") {
+		defaults := []string{"/usr/share/snmp/mibs"}
+		if home, err := os.UserHomeDir(); err == nil {
+			defaults = append([]string{filepath.Join(home, ".snmp", "mibs")}, defaults...)
+		}
+		env := os.Getenv("MIBDIRS")
+		switch {
+		case env == "":
+			return defaults
+		case strings.HasPrefix(env, "+"):
+			return append(defaults, filepath.SplitList(env[1:])...)
+		default:
+			return filepath.SplitList(env)
+		}
+	}
+	return paths
+}
+
+// Options controlling MIB parsing, see --snmp.mibopts.
+type mibOptions struct {
+	// Whether "--" ends a comment, rather than only the end of the line.
+	dashEndsComment bool
+	// Whether conflicting definitions of an OID are replaced by later modules.
+	replace bool
+}
+
+func parseMIBOpts(opts string) (mibOptions, error) {
+	o := mibOptions{dashEndsComment: true}
+	for _, c := range opts {
+		switch c {
+		case 'c':
+			o.dashEndsComment = false
+		case 'R':
+			o.replace = true
+		case 'u', 'd', 'e', 'w', 'W':
+			// Underscores and descriptions are always allowed, and conflicts
+			// are never errors.
+		default:
+			return o, fmt.Errorf("unknown MIB option %q", c)
+		}
+	}
+	return o, nil
+}
+
+// Load all the MIBs in the given directories, returning the tree under iso
+// and the problems found in the MIBs. If cachePath is set, the result is
+// cached there and reused as long as the MIBs and options are unchanged.
+func loadMIBs(dirs []string, opts, cachePath string, logger log.Logger) (*Node, []*ParseError, error) {
+	options, err := parseMIBOpts(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	level.Info(logger).Log("msg", "Loading MIBs", "from", strings.Join(dirs, string(filepath.ListSeparator)))
+
+	files, err := readMIBFiles(dirs, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := mibCacheKey(files, opts)
+	if cachePath != "" {
+		c, err := readMIBCache(cachePath, key)
+		if err == nil {
+			level.Info(logger).Log("msg", "Loaded MIBs from cache", "file", cachePath)
+			return c.Nodes, c.ParseErrors, nil
+		}
+		level.Debug(logger).Log("msg", "Not using MIB cache", "file", cachePath, "err", err)
+	}
+
+	r := newMIBResolver(options, logger)
+	for _, f := range files {
+		r.addFile(f.path, f.content)
+	}
+	nodes := r.buildTree()
+	if cachePath != "" {
+		err := writeMIBCache(cachePath, &mibCache{Version: mibCacheVersion, Key: key, Nodes: nodes, ParseErrors: r.errors})
+		if err != nil {
+			level.Warn(logger).Log("msg", "Unable to write MIB cache", "file", cachePath, "err", err)
+		}
+	}
+	return nodes, r.errors, nil
+}
+
+type mibFile struct {
+	path    string
+	content string
+}
+
+// Read all the MIB files in the given directories, in load order.
+func readMIBFiles(dirs []string, logger log.Logger) ([]mibFile, error) {
+	files := []mibFile{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				level.Warn(logger).Log("msg", "MIB directory does not exist", "dir", dir)
+				continue
+			}
+			return nil, fmt.Errorf("error reading MIB directory: %w", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading MIB file: %w", err)
+			}
+			files = append(files, mibFile{path: path, content: string(content)})
+		}
+	}
+	return files, nil
+}
+
+type mibResolver struct {
+	options mibOptions
+	logger  log.Logger
+
+	// Modules in the order they were loaded.
+	modules []*mibModule
+	byName  map[string]*mibModule
+	objects map[*mibModule]map[string]*mibObject
+	// The first definition of each object and type in any module.
+	globalObjects map[string]*mibObject
+	globalTypes   map[string]*mibType
+	resolved      map[*mibObject][]int64
+	errors        []*ParseError
+	syntaxErrors  []*ParseError
+}
+
+func newMIBResolver(options mibOptions, logger log.Logger) *mibResolver {
+	return &mibResolver{
+		options:       options,
+		logger:        logger,
+		byName:        map[string]*mibModule{},
+		objects:       map[*mibModule]map[string]*mibObject{},
+		globalObjects: map[string]*mibObject{},
+		globalTypes:   map[string]*mibType{},
+		resolved:      map[*mibObject][]int64{},
+	}
+}
+
+func (r *mibResolver) addFile(file, content string) {
+	modules, errs := parseMIBModules(file, content, r.options.dashEndsComment)
+	for _, err := range errs {
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			pe = &ParseError{File: file, Message: err.Error()}
+		}
+		pe.Severity = severityError
+		r.errors = append(r.errors, pe)
+		r.syntaxErrors = append(r.syntaxErrors, pe)
+	}
+	for _, m := range modules {
+		if prev, ok := r.byName[m.Name]; ok {
+			level.Debug(r.logger).Log("msg", "Module already loaded, ignoring", "module", m.Name, "file", file, "loaded_from", prev.File)
+			continue
+		}
+		r.modules = append(r.modules, m)
+		r.byName[m.Name] = m
+		objects := make(map[string]*mibObject, len(m.Objects))
+		for _, o := range m.Objects {
+			objects[o.Name] = o
+			if _, ok := r.globalObjects[o.Name]; !ok {
+				r.globalObjects[o.Name] = o
+			}
+		}
+		r.objects[m] = objects
+		for name, t := range m.Types {
+			if _, ok := r.globalTypes[name]; !ok {
+				r.globalTypes[name] = t
+			}
+		}
+	}
+}
+
+func (r *mibResolver) report(m *mibModule, line int, severity string, format string, args ...interface{}) *ParseError {
+	e := &ParseError{File: m.File, Line: line, Module: m.Name, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	r.errors = append(r.errors, e)
+	return e
+}
+
+// Report a problem with an object, noting if it is due to a missing import
+// of symbol by the importer module.
+func (r *mibResolver) reportObject(m *mibModule, o *mibObject, importer *mibModule, symbol string, format string, args ...interface{}) {
+	e := r.report(m, o.Line, severityError, format, args...)
+	e.Object = o.Name
+	if from, ok := importer.Imports[symbol]; ok && r.byName[from] == nil {
+		e.MissingImport = from
+	}
+}
+
+// Whether a symbol is defined anywhere.
+func (r *mibResolver) defined(s string) bool {
+	_, object := r.globalObjects[s]
+	_, typ := r.globalTypes[s]
+	_, oid := smiWellKnownOids[s]
+	return object || typ || oid
+}
+
+// Check that all imports can be satisfied. Imports which can be found in
+// another module are only warnings.
+func (r *mibResolver) checkImports(m *mibModule) {
+	symbols := make([]string, 0, len(m.Imports))
+	for s := range m.Imports {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	// Symbols imported from each missing module.
+	missing := map[string][]string{}
+	missingModules := []string{}
+	for _, s := range symbols {
+		from := m.Imports[s]
+		if _, ok := smiBaseTypes[s]; ok || smiMacros[s] {
+			continue
+		}
+		fm, ok := r.byName[from]
+		if !ok {
+			if _, ok := smiWellKnownOids[s]; ok && smiModules[from] {
+				continue
+			}
+			if _, ok := missing[from]; !ok {
+				missingModules = append(missingModules, from)
+			}
+			missing[from] = append(missing[from], s)
+			continue
+		}
+		if _, ok := r.objects[fm][s]; ok {
+			continue
+		}
+		if _, ok := fm.Types[s]; ok {
+			continue
+		}
+		severity := severityWarning
+		if !r.defined(s) {
+			severity = severityError
+		}
+		r.report(m, m.ImportLines[s], severity, "%s not found in module %s", s, from)
+	}
+	for _, from := range missingModules {
+		severity := severityWarning
+		for _, s := range missing[from] {
+			if !r.defined(s) {
+				severity = severityError
+			}
+		}
+		e := r.report(m, m.ImportLines[missing[from][0]], severity, "cannot find module %s, imported for %s", from, strings.Join(missing[from], ", "))
+		e.MissingImport = from
+	}
+}
+
+// Find the definition of an object, following the module's imports.
+// Sloppy MIBs don't always import what they use, so fall back to any
+// module that defines it.
+func (r *mibResolver) lookupObject(m *mibModule, name string) *mibObject {
+	if o, ok := r.objects[m][name]; ok {
+		return o
+	}
+	if from, ok := m.Imports[name]; ok {
+		if fm, ok := r.byName[from]; ok {
+			if o, ok := r.objects[fm][name]; ok {
+				return o
+			}
+		}
+	}
+	return r.globalObjects[name]
+}
+
+// Find the definition of a type, returning it and the module it's in.
+func (r *mibResolver) lookupType(m *mibModule, name string) (*mibType, *mibModule) {
+	if t, ok := m.Types[name]; ok {
+		return t, m
+	}
+	if from, ok := m.Imports[name]; ok {
+		if fm, ok := r.byName[from]; ok {
+			if t, ok := fm.Types[name]; ok {
+				return t, fm
+			}
+		}
+	}
+	if t, ok := r.globalTypes[name]; ok {
+		return t, r.byName[t.Module]
+	}
+	return nil, nil
+}
+
+func oidFromString(s string) []int64 {
+	oid := []int64{}
+	for _, p := range strings.Split(s, ".") {
+		i, _ := strconv.ParseInt(p, 10, 64)
+		oid = append(oid, i)
+	}
+	return oid
+}
+
+func oidToString(oid []int64) string {
+	parts := make([]string, len(oid))
+	for i, o := range oid {
+		parts[i] = strconv.FormatInt(o, 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Try to resolve the OID of an object. Returns false if its parent
+// is not resolved yet.
+func (r *mibResolver) resolveOid(m *mibModule, o *mibObject) ([]int64, bool) {
+	first := o.Oid[0]
+	var oid []int64
+	switch {
+	case first.Name == "":
+		oid = []int64{first.Number}
+	default:
+		if parent := r.lookupObject(m, first.Name); parent != nil {
+			p, ok := r.resolved[parent]
+			if !ok {
+				return nil, false
+			}
+			oid = append(oid, p...)
+		} else if s, ok := smiWellKnownOids[first.Name]; ok {
+			oid = oidFromString(s)
+		} else if first.HasNumber {
+			oid = []int64{first.Number}
+		} else {
+			return nil, false
+		}
+	}
+	for _, c := range o.Oid[1:] {
+		if !c.HasNumber {
+			return nil, false
+		}
+		oid = append(oid, c.Number)
+	}
+	return oid, true
+}
+
+type resolvedSyntax struct {
+	typ               string
+	hint              string
+	textualConvention string
+	fixedSize         int
+	enumValues        map[int]string
+}
+
+// Resolve a SYNTAX down to its base type, following textual conventions.
+func (r *mibResolver) resolveSyntax(m *mibModule, o *mibObject) resolvedSyntax {
+	s := o.Syntax
+	rs := resolvedSyntax{typ: "OTHER", enumValues: s.EnumValues}
+	switch {
+	case s.Sequence:
+		return rs
+	case s.Base != "":
+		rs.typ = s.Base
+		return rs
+	}
+
+	t, tm := r.lookupType(m, s.TypeRef)
+	if t == nil {
+		r.reportObject(m, o, m, s.TypeRef, "unknown type %s for %s", s.TypeRef, o.Name)
+		return rs
+	}
+	if t.Syntax.Sequence {
+		return rs
+	}
+	rs.textualConvention = t.Name
+	rs.hint = t.Hint
+	if len(t.Syntax.Ranges) == 1 && t.Syntax.Ranges[0].Low == t.Syntax.Ranges[0].High {
+		rs.fixedSize = int(t.Syntax.Ranges[0].Low)
+	}
+	// Some textual conventions are defined in terms of others.
+	for depth := 0; t.Syntax.TypeRef != ""; depth++ {
+		if len(rs.enumValues) == 0 {
+			rs.enumValues = t.Syntax.EnumValues
+		}
+		next, nm := r.lookupType(tm, t.Syntax.TypeRef)
+		if next == nil || depth > 10 {
+			// The type is missing from the module of the one referring to it.
+			r.reportObject(m, o, tm, t.Syntax.TypeRef, "unknown type %s for %s, used by %s", t.Syntax.TypeRef, t.Name, o.Name)
+			return rs
+		}
+		if rs.hint == "" {
+			rs.hint = next.Hint
+		}
+		t, tm = next, nm
+	}
+	if len(rs.enumValues) == 0 {
+		rs.enumValues = t.Syntax.EnumValues
+	}
+	rs.typ = t.Syntax.Base
+	return rs
+}
+
+// Resolve all the loaded modules into a tree of nodes, returning the iso node.
+func (r *mibResolver) buildTree() *Node {
+	for _, m := range r.modules {
+		r.checkImports(m)
+	}
+
+	// Objects can refer to parents defined later or in other modules,
+	// so keep going until no more can be resolved.
+	pending := map[*mibObject]*mibModule{}
+	for _, m := range r.modules {
+		for _, o := range m.Objects {
+			pending[o] = m
+		}
+	}
+	for progress := true; progress; {
+		progress = false
+		for _, m := range r.modules {
+			for _, o := range m.Objects {
+				if _, ok := pending[o]; !ok {
+					continue
+				}
+				if oid, ok := r.resolveOid(m, o); ok {
+					r.resolved[o] = oid
+					delete(pending, o)
+					progress = true
+				}
+			}
+		}
+	}
+	for _, m := range r.modules {
+		for _, o := range m.Objects {
+			if _, ok := pending[o]; ok {
+				r.reportObject(m, o, m, o.Oid[0].Name, "cannot resolve parent %s of %s", o.Oid[0].Name, o.Name)
+			}
+		}
+	}
+
+	tb := &treeBuilder{nodes: map[string]*Node{}, defined: map[*Node]*mibObject{}}
+	for label, oid := range smiWellKnownOids {
+		tb.node(oidFromString(oid)).Label = label
+	}
+	for _, m := range r.modules {
+		for _, o := range m.Objects {
+			oid, ok := r.resolved[o]
+			if !ok {
+				continue
+			}
+			// Named components such as org(3) also define nodes.
+			if len(o.Oid) > 1 {
+				base := len(oid) - len(o.Oid) + 1
+				for i, c := range o.Oid[1 : len(o.Oid)-1] {
+					if n := tb.node(oid[:base+i+1]); c.Name != "" && n.Label == "" {
+						n.Label = c.Name
+					}
+				}
+			}
+			tb.define(r, m, o, oid)
+		}
+	}
+
+	// Attach OIDs to problems, so they can be matched to what is walked.
+	for _, e := range r.errors {
+		m, ok := r.byName[e.Module]
+		if !ok || m.File != e.File || e.Oid != "" || e.Object == "" {
+			continue
+		}
+		if oid, ok := r.resolved[r.objects[m][e.Object]]; ok {
+			e.Oid = oidToString(oid)
+		}
+	}
+	// The definition with a syntax error is missing, and is most likely
+	// under the module's identity.
+	for _, e := range r.syntaxErrors {
+		m, ok := r.byName[e.Module]
+		if !ok || m.File != e.File {
+			continue
+		}
+		for _, o := range m.Objects {
+			if oid, ok := r.resolved[o]; ok && o.Kind == "MODID" {
+				e.Oid = oidToString(oid)
+				break
+			}
+		}
+	}
+
+	root, ok := tb.nodes["1"]
+	if !ok {
+		root = tb.node([]int64{1})
+	}
+	walkNode(root, func(n *Node) {
+		sort.Slice(n.Children, func(i, j int) bool {
+			return n.Children[i].subid < n.Children[j].subid
+		})
+	})
+	return root
+}
+
+type treeBuilder struct {
+	nodes map[string]*Node
+	// The object each node was defined by.
+	defined map[*Node]*mibObject
+}
+
+// Get the node for an OID, creating it and its parents if needed.
+func (tb *treeBuilder) node(oid []int64) *Node {
+	key := oidToString(oid)
+	if n, ok := tb.nodes[key]; ok {
+		return n
+	}
+	n := &Node{
+		Oid:        key,
+		subid:      oid[len(oid)-1],
+		Type:       "OTHER",
+		Access:     "unknown",
+		EnumValues: map[int]string{},
+	}
+	tb.nodes[key] = n
+	if len(oid) > 1 {
+		parent := tb.node(oid[:len(oid)-1])
+		parent.Children = append(parent.Children, n)
+		if parent.Indexes == nil {
+			parent.Indexes = []string{}
+		}
+	}
+	return n
+}
+
+func (tb *treeBuilder) define(r *mibResolver, m *mibModule, o *mibObject, oid []int64) {
+	n := tb.node(oid)
+	if prev, ok := tb.defined[n]; ok {
+		// Prefer a full definition over a plain OBJECT IDENTIFIER or trap.
+		richer := (prev.Kind == "OTHER" || prev.Kind == "TRAPTYPE") && o.Kind != "OTHER" && o.Kind != "TRAPTYPE"
+		if !richer && (prev.Name == o.Name || !r.options.replace) {
+			if prev.Name != o.Name {
+				e := r.report(m, o.Line, severityWarning, "%s has the same OID as %s, ignoring it", o.Name, prev.Name)
+				e.Object, e.Oid = o.Name, n.Oid
+			}
+			return
+		}
+	}
+	tb.defined[n] = o
+
+	n.Label = o.Name
+	n.Module = m.Name
+	n.Members = o.Members
+	n.Description = o.Description
+	n.Units = o.Units
+	n.Augments = o.Augments
+	n.ImpliedIndex = o.ImpliedIndex
+	if o.Indexes != nil {
+		n.Indexes = append([]string{}, o.Indexes...)
+	}
+	n.Access = "unknown"
+	if o.Access != "" {
+		n.Access = o.Access
+	}
+	n.Type = o.Kind
+	n.EnumValues = map[int]string{}
+	if o.Syntax != nil {
+		rs := r.resolveSyntax(m, o)
+		n.Type = rs.typ
+		n.Hint = rs.hint
+		n.TextualConvention = rs.textualConvention
+		n.FixedSize = rs.fixedSize
+		for k, v := range rs.enumValues {
+			n.EnumValues[k] = v
+		}
+	}
+}
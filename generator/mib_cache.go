@@ -0,0 +1,96 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+)
+
+// Increment when the cached structures or the way MIBs are parsed change, so
+// that old caches are not used.
+const mibCacheVersion = 4
+
+// The parsed MIBs, as stored in the cache file.
+type mibCache struct {
+	Version     int
+	Key         string
+	Nodes       *Node
+	ParseErrors []*ParseError
+}
+
+// A hash of everything the parsed MIBs depend on.
+func mibCacheKey(files []mibFile, opts string) string {
+	h := sha256.New()
+	writeHashString(h, opts)
+	for _, f := range files {
+		writeHashString(h, f.path)
+		writeHashString(h, f.content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Write a length prefixed string, so that boundaries are unambiguous.
+func writeHashString(h hash.Hash, s string) {
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(s)))
+	h.Write(l[:])
+	h.Write([]byte(s))
+}
+
+func readMIBCache(path, key string) (*mibCache, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c := &mibCache{}
+	if err := json.NewDecoder(f).Decode(c); err != nil {
+		return nil, fmt.Errorf("error decoding MIB cache: %s", err)
+	}
+	if c.Version != mibCacheVersion {
+		return nil, fmt.Errorf("MIB cache version %d, expected %d", c.Version, mibCacheVersion)
+	}
+	if c.Key != key {
+		return nil, fmt.Errorf("MIBs or options changed")
+	}
+	if c.Nodes == nil {
+		return nil, fmt.Errorf("MIB cache has no nodes")
+	}
+	return c, nil
+}
+
+// Write the cache to a temporary file first, so that an interrupted run
+// doesn't leave a truncated cache behind.
+func writeMIBCache(path string, c *mibCache) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := json.NewEncoder(f).Encode(c); err != nil {
+		f.Close()
+		return fmt.Errorf("error encoding MIB cache: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,1063 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	// A quoted binary or hex string such as '0A'H.
+	tokBinString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return "quoted string"
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// Lexer for the subset of ASN.1 used by SMIv1 and SMIv2 MIBs.
+type mibLexer struct {
+	src  string
+	pos  int
+	line int
+	// Whether "--" ends a comment as well as starting one, as per ASN.1.
+	dashEndsComment bool
+}
+
+func newMIBLexer(src string, dashEndsComment bool) *mibLexer {
+	return &mibLexer{src: src, line: 1, dashEndsComment: dashEndsComment}
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '-' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Skip whitespace and comments.
+func (l *mibLexer) skipSpace() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case c == '-' && strings.HasPrefix(l.src[l.pos:], "--"):
+			l.pos += 2
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				// Runs of dashes used as separators don't end the comment.
+				if l.dashEndsComment && strings.HasPrefix(l.src[l.pos:], "--") && !strings.HasPrefix(l.src[l.pos:], "---") {
+					l.pos += 2
+					break
+				}
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *mibLexer) next() token {
+	l.skipSpace()
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, line: l.line}
+	}
+	start, line := l.pos, l.line
+	c := l.src[l.pos]
+	switch {
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			// A "--" always starts a comment, even inside an identifier.
+			if strings.HasPrefix(l.src[l.pos:], "--") {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], line: line}
+	case isDigit(c) || (c == '-' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1])):
+		l.pos++
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], line: line}
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\n' {
+				l.line++
+			}
+			l.pos++
+		}
+		text := l.src[start+1 : l.pos]
+		if l.pos < len(l.src) {
+			l.pos++
+		}
+		return token{kind: tokString, text: text, line: line}
+	case c == '\'':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '\'' && l.src[l.pos] != '\n' {
+			l.pos++
+		}
+		text := l.src[start+1 : l.pos]
+		if l.pos < len(l.src) && l.src[l.pos] == '\'' {
+			l.pos++
+		}
+		// The radix follows the closing quote.
+		if l.pos < len(l.src) && strings.ContainsRune("hHbB", rune(l.src[l.pos])) {
+			text += "'" + string(l.src[l.pos])
+			l.pos++
+		}
+		return token{kind: tokBinString, text: text, line: line}
+	case strings.HasPrefix(l.src[l.pos:], "::="):
+		l.pos += 3
+	case strings.HasPrefix(l.src[l.pos:], ".."):
+		l.pos += 2
+	default:
+		l.pos++
+	}
+	return token{kind: tokPunct, text: l.src[start:l.pos], line: line}
+}
+
+// One component of an OID value, such as "org(3)", "6" or "mib-2".
+type mibOidComponent struct {
+	Name      string
+	Number    int64
+	HasNumber bool
+}
+
+type mibRange struct {
+	Low, High int64
+}
+
+// The SYNTAX of an object or type.
+type mibSyntax struct {
+	// One of the NetSNMP type names, e.g. INTEGER or OCTETSTR. Empty if
+	// the syntax refers to another type.
+	Base string
+	// Name of the referenced type or textual convention.
+	TypeRef string
+	// Set for SEQUENCE and SEQUENCE OF, which are tables and rows.
+	Sequence   bool
+	EnumValues map[int]string
+	Ranges     []mibRange
+}
+
+// A type assignment or TEXTUAL-CONVENTION.
+type mibType struct {
+	Name              string
+	Module            string
+	Line              int
+	TextualConvention bool
+	Hint              string
+	Description       string
+	Syntax            *mibSyntax
+}
+
+// A definition which has an OID.
+type mibObject struct {
+	Name string
+	Line int
+	// The NetSNMP type of the node, e.g. OBJGROUP for an OBJECT-GROUP.
+	// Empty for OBJECT-TYPEs, where it comes from the SYNTAX.
+	Kind         string
+	Oid          []mibOidComponent
+	Syntax       *mibSyntax
+	Units        string
+	Access       string
+	Description  string
+	Indexes      []string
+	ImpliedIndex bool
+	Augments     string
+	// The objects of a group, or the groups of a compliance statement.
+	// Groups from other modules are qualified as MODULE::name.
+	Members []string
+}
+
+type mibModule struct {
+	Name string
+	File string
+	Line int
+	// Symbol to the module it is imported from.
+	Imports map[string]string
+	// Symbol to the line it is imported on.
+	ImportLines map[string]int
+	Objects     []*mibObject
+	Types       map[string]*mibType
+}
+
+// Base types built into the SMI, and their NetSNMP names.
+var smiBaseTypes = map[string]string{
+	"INTEGER":        "INTEGER",
+	"Integer32":      "INTEGER32",
+	"Unsigned32":     "UNSIGNED32",
+	"UInteger32":     "UINTEGER",
+	"Counter":        "COUNTER",
+	"Counter32":      "COUNTER",
+	"Counter64":      "COUNTER64",
+	"Gauge":          "GAUGE",
+	"Gauge32":        "GAUGE",
+	"TimeTicks":      "TIMETICKS",
+	"IpAddress":      "IPADDR",
+	"NetworkAddress": "NETADDR",
+	"Opaque":         "OPAQUE",
+	"NsapAddress":    "NSAPADDRESS",
+	"NULL":           "NULL",
+}
+
+// Macros which are followed by clauses and then "::= { oid }".
+var smiMacroKinds = map[string]string{
+	"MODULE-IDENTITY":    "MODID",
+	"OBJECT-IDENTITY":    "OBJIDENTITY",
+	"NOTIFICATION-TYPE":  "NOTIFTYPE",
+	"OBJECT-GROUP":       "OBJGROUP",
+	"NOTIFICATION-GROUP": "NOTIFGROUP",
+	"MODULE-COMPLIANCE":  "MODCOMP",
+	"AGENT-CAPABILITIES": "AGENTCAP",
+}
+
+var smiAccess = map[string]string{
+	"read-only":             "ACCESS_READONLY",
+	"read-write":            "ACCESS_READWRITE",
+	"write-only":            "ACCESS_WRITEONLY",
+	"not-accessible":        "ACCESS_NOACCESS",
+	"accessible-for-notify": "ACCESS_NOTIFY",
+	"read-create":           "ACCESS_CREATE",
+}
+
+type mibParser struct {
+	lex    *mibLexer
+	tok    token
+	peek   []token
+	file   string
+	module string
+	// The object being defined, if any.
+	object string
+	// The number of tokens consumed so far.
+	consumed int
+	// Errors in definitions, which were skipped.
+	errors []error
+}
+
+// Parse all the modules in a MIB file. A definition with a syntax error is
+// skipped, as NetSNMP does, so there can be several errors.
+func parseMIBModules(file, src string, dashEndsComment bool) ([]*mibModule, []error) {
+	p := &mibParser{lex: newMIBLexer(src, dashEndsComment), file: file}
+	p.advance()
+	modules := []*mibModule{}
+	for p.tok.kind != tokEOF {
+		m, err := p.parseModule()
+		if m != nil {
+			modules = append(modules, m)
+		}
+		if err != nil {
+			return modules, append(p.errors, err)
+		}
+	}
+	return modules, p.errors
+}
+
+func (p *mibParser) advance() {
+	p.consumed++
+	if len(p.peek) > 0 {
+		p.tok = p.peek[0]
+		p.peek = p.peek[1:]
+		return
+	}
+	p.tok = p.lex.next()
+}
+
+// Look ahead n tokens past the current one.
+func (p *mibParser) lookahead(n int) token {
+	for len(p.peek) < n {
+		p.peek = append(p.peek, p.lex.next())
+	}
+	return p.peek[n-1]
+}
+
+func (p *mibParser) errorf(format string, args ...interface{}) error {
+	return &ParseError{File: p.file, Line: p.tok.line, Module: p.module, Object: p.object, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *mibParser) is(text string) bool {
+	return (p.tok.kind == tokIdent || p.tok.kind == tokPunct) && p.tok.text == text
+}
+
+func (p *mibParser) expect(text string) error {
+	if !p.is(text) {
+		return p.errorf("expected %q, found %s", text, p.tok)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *mibParser) expectIdent() (string, error) {
+	if p.tok.kind != tokIdent {
+		return "", p.errorf("expected identifier, found %s", p.tok)
+	}
+	s := p.tok.text
+	p.advance()
+	return s, nil
+}
+
+func (p *mibParser) expectString() (string, error) {
+	if p.tok.kind != tokString {
+		return "", p.errorf("expected quoted string, found %s", p.tok)
+	}
+	s := p.tok.text
+	p.advance()
+	return s, nil
+}
+
+func (p *mibParser) expectNumber() (int64, error) {
+	var n int64
+	var err error
+	switch p.tok.kind {
+	case tokNumber:
+		n, err = strconv.ParseInt(p.tok.text, 10, 64)
+		if err != nil {
+			// Values such as 18446744073709551615 for Counter64 ranges.
+			var u uint64
+			u, err = strconv.ParseUint(p.tok.text, 10, 64)
+			n = int64(u)
+		}
+	case tokBinString:
+		n, err = parseBinString(p.tok.text)
+	default:
+		return 0, p.errorf("expected number, found %s", p.tok)
+	}
+	if err != nil {
+		return 0, p.errorf("invalid number %s: %s", p.tok, err)
+	}
+	p.advance()
+	return n, nil
+}
+
+// Convert a lexed '0A'H or '1010'B to a number.
+func parseBinString(s string) (int64, error) {
+	i := strings.LastIndex(s, "'")
+	if i < 0 {
+		return 0, fmt.Errorf("missing radix")
+	}
+	digits, radix := s[:i], 16
+	if strings.EqualFold(s[i+1:], "b") {
+		radix = 2
+	}
+	if digits == "" {
+		return 0, nil
+	}
+	u, err := strconv.ParseUint(digits, radix, 64)
+	return int64(u), err
+}
+
+// Skip a balanced {...} or (...) group. The current token must be the opening one.
+func (p *mibParser) skipGroup() error {
+	open := p.tok.text
+	closing := map[string]string{"{": "}", "(": ")", "[": "]"}[open]
+	depth := 0
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.errorf("unterminated %q", open)
+		case p.is(open):
+			depth++
+		case p.is(closing):
+			depth--
+		}
+		p.advance()
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (p *mibParser) parseModule() (*mibModule, error) {
+	m := &mibModule{
+		File:        p.file,
+		Line:        p.tok.line,
+		Imports:     map[string]string{},
+		ImportLines: map[string]int{},
+		Types:       map[string]*mibType{},
+	}
+	var err error
+	m.Name, err = p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	p.module = m.Name
+	if p.is("{") {
+		// An ASN.1 module identifier, which SMI doesn't use.
+		if err := p.skipGroup(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	// Tagging defaults such as "IMPLICIT TAGS".
+	for p.tok.kind == tokIdent {
+		p.advance()
+	}
+	if err := p.expect("::="); err != nil {
+		return nil, err
+	}
+	if err := p.expect("BEGIN"); err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return m, p.errorf("missing END of module %s", m.Name)
+		case p.is("END"):
+			p.advance()
+			return m, nil
+		case p.is("EXPORTS"):
+			for !p.is(";") && p.tok.kind != tokEOF {
+				p.advance()
+			}
+			p.advance()
+		case p.is("IMPORTS"):
+			start := p.consumed
+			p.advance()
+			if err := p.parseImports(m); err != nil {
+				p.errors = append(p.errors, err)
+				p.resync(start)
+			}
+		default:
+			start := p.consumed
+			if err := p.parseDefinition(m); err != nil {
+				p.errors = append(p.errors, err)
+				p.resync(start)
+			}
+			p.object = ""
+		}
+	}
+}
+
+// Skip to the start of the next definition or the END of the module after
+// a syntax error, so that only the broken definition is lost. start is the
+// number of tokens consumed when the broken definition started.
+func (p *mibParser) resync(start int) {
+	if p.consumed == start {
+		p.advance()
+	}
+	for p.tok.kind != tokEOF && !p.is("END") && !p.atDefinition() {
+		p.advance()
+	}
+}
+
+// Whether the current token starts a definition, such as "Name ::=",
+// "NAME MACRO", "name OBJECT-TYPE" or "name OBJECT IDENTIFIER".
+func (p *mibParser) atDefinition() bool {
+	if p.tok.kind != tokIdent {
+		return false
+	}
+	next := p.lookahead(1)
+	if next.kind != tokIdent && next.kind != tokPunct {
+		return false
+	}
+	if c := p.tok.text[0]; c >= 'A' && c <= 'Z' {
+		// Types and macros. Clauses such as "SYNTAX OBJECT IDENTIFIER"
+		// are also capitalised.
+		return next.text == "::=" || next.text == "MACRO"
+	}
+	switch next.text {
+	case "OBJECT-TYPE", "TRAP-TYPE":
+		return true
+	case "OBJECT":
+		return p.lookahead(2).text == "IDENTIFIER"
+	}
+	return smiMacroKinds[next.text] != ""
+}
+
+func (p *mibParser) parseImports(m *mibModule) error {
+	symbols := []token{}
+	for !p.is(";") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.errorf("unterminated IMPORTS")
+		case p.is(","):
+			p.advance()
+		case p.is("FROM"):
+			p.advance()
+			from, err := p.expectIdent()
+			if err != nil {
+				return err
+			}
+			if p.is("{") {
+				if err := p.skipGroup(); err != nil {
+					return err
+				}
+			}
+			for _, s := range symbols {
+				m.Imports[s.text] = from
+				m.ImportLines[s.text] = s.line
+			}
+			symbols = symbols[:0]
+		case p.tok.kind == tokIdent:
+			symbols = append(symbols, p.tok)
+			p.advance()
+		default:
+			return p.errorf("unexpected %s in IMPORTS", p.tok)
+		}
+	}
+	p.advance()
+	if len(symbols) != 0 {
+		return p.errorf("%s is missing FROM in IMPORTS", symbols[0].text)
+	}
+	return nil
+}
+
+func (p *mibParser) parseDefinition(m *mibModule) error {
+	line := p.tok.line
+	name, err := p.expectIdent()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p.is("::="):
+		p.advance()
+		t := &mibType{Name: name, Module: m.Name, Line: line}
+		if p.is("TEXTUAL-CONVENTION") {
+			p.advance()
+			if err := p.parseTextualConvention(t); err != nil {
+				return err
+			}
+		} else {
+			t.Syntax, err = p.parseSyntax()
+			if err != nil {
+				return err
+			}
+		}
+		// Base types are redefined in the SMI modules themselves.
+		if _, ok := smiBaseTypes[name]; !ok {
+			m.Types[name] = t
+		}
+		return nil
+	case p.is("MACRO"):
+		// Macros are only found in the SMI modules, and are built in.
+		for !p.is("END") {
+			if p.tok.kind == tokEOF {
+				return p.errorf("unterminated MACRO %s", name)
+			}
+			p.advance()
+		}
+		p.advance()
+		return nil
+	}
+
+	o := &mibObject{Name: name, Line: line}
+	p.object = name
+	switch {
+	case p.is("OBJECT") && p.lookahead(1).text == "IDENTIFIER":
+		p.advance()
+		p.advance()
+		o.Kind = "OTHER"
+	case p.is("OBJECT-TYPE"):
+		p.advance()
+		if err := p.parseObjectType(o); err != nil {
+			return err
+		}
+	case p.is("TRAP-TYPE"):
+		p.advance()
+		return p.parseTrapType(m, o)
+	case smiMacroKinds[p.tok.text] != "":
+		o.Kind = smiMacroKinds[p.tok.text]
+		p.advance()
+		// The module the groups of a compliance statement are in.
+		complianceModule := ""
+		for !p.is("::=") {
+			switch {
+			case p.tok.kind == tokEOF:
+				return p.errorf("missing ::= for %s", name)
+			case p.is("DESCRIPTION") && o.Description == "":
+				// Later descriptions belong to REVISION clauses.
+				p.advance()
+				if o.Description, err = p.expectString(); err != nil {
+					return err
+				}
+			case (p.is("OBJECTS") && o.Kind == "OBJGROUP") || (p.is("NOTIFICATIONS") && o.Kind == "NOTIFGROUP"):
+				p.advance()
+				if o.Members, err = p.parseNameList(); err != nil {
+					return err
+				}
+			case p.is("MODULE") && o.Kind == "MODCOMP":
+				p.advance()
+				complianceModule = ""
+				if p.tok.kind == tokIdent && !p.is("MANDATORY-GROUPS") && !p.is("GROUP") && !p.is("OBJECT") {
+					complianceModule = p.tok.text
+					p.advance()
+					if p.is("{") {
+						if err := p.skipGroup(); err != nil {
+							return err
+						}
+					}
+				}
+			case p.is("MANDATORY-GROUPS") && o.Kind == "MODCOMP":
+				p.advance()
+				groups, err := p.parseNameList()
+				if err != nil {
+					return err
+				}
+				for _, g := range groups {
+					o.Members = append(o.Members, qualifyName(complianceModule, m.Name, g))
+				}
+			case p.is("GROUP") && o.Kind == "MODCOMP":
+				p.advance()
+				g, err := p.expectIdent()
+				if err != nil {
+					return err
+				}
+				o.Members = append(o.Members, qualifyName(complianceModule, m.Name, g))
+			default:
+				p.advance()
+			}
+		}
+	default:
+		// A value of some other type, such as "foo INTEGER ::= 1".
+		if _, err := p.parseSyntax(); err != nil {
+			return err
+		}
+		if err := p.expect("::="); err != nil {
+			return err
+		}
+		if p.is("{") {
+			return p.skipGroup()
+		}
+		p.advance()
+		return nil
+	}
+
+	if err := p.expect("::="); err != nil {
+		return err
+	}
+	if o.Oid, err = p.parseOid(); err != nil {
+		return err
+	}
+	m.Objects = append(m.Objects, o)
+	return nil
+}
+
+func (p *mibParser) parseTextualConvention(t *mibType) error {
+	var err error
+	t.TextualConvention = true
+	for {
+		switch {
+		case p.is("DISPLAY-HINT"):
+			p.advance()
+			t.Hint, err = p.expectString()
+		case p.is("STATUS"):
+			p.advance()
+			_, err = p.expectIdent()
+		case p.is("DESCRIPTION"):
+			p.advance()
+			t.Description, err = p.expectString()
+		case p.is("REFERENCE"):
+			p.advance()
+			_, err = p.expectString()
+		case p.is("SYNTAX"):
+			p.advance()
+			t.Syntax, err = p.parseSyntax()
+			return err
+		default:
+			return p.errorf("unexpected %s in TEXTUAL-CONVENTION %s", p.tok, t.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *mibParser) parseObjectType(o *mibObject) error {
+	var err error
+	for !p.is("::=") {
+		switch {
+		case p.is("SYNTAX"):
+			p.advance()
+			o.Syntax, err = p.parseSyntax()
+		case p.is("UNITS"):
+			p.advance()
+			o.Units, err = p.expectString()
+		case p.is("MAX-ACCESS") || p.is("ACCESS"):
+			p.advance()
+			if a, ok := smiAccess[p.tok.text]; ok && p.tok.kind == tokIdent {
+				o.Access = a
+				p.advance()
+			} else {
+				err = p.errorf("unknown access %s for %s", p.tok, o.Name)
+			}
+		case p.is("STATUS"):
+			p.advance()
+			_, err = p.expectIdent()
+		case p.is("DESCRIPTION"):
+			p.advance()
+			o.Description, err = p.expectString()
+		case p.is("REFERENCE"):
+			p.advance()
+			_, err = p.expectString()
+		case p.is("INDEX"):
+			p.advance()
+			err = p.parseIndex(o)
+		case p.is("AUGMENTS"):
+			p.advance()
+			if err = p.expect("{"); err == nil {
+				if o.Augments, err = p.expectIdent(); err == nil {
+					err = p.expect("}")
+				}
+			}
+		case p.is("DEFVAL"):
+			p.advance()
+			if !p.is("{") {
+				return p.errorf("expected \"{\" after DEFVAL, found %s", p.tok)
+			}
+			err = p.skipGroup()
+		default:
+			return p.errorf("unexpected %s in OBJECT-TYPE %s", p.tok, o.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if o.Syntax == nil {
+		return p.errorf("missing SYNTAX for %s", o.Name)
+	}
+	return nil
+}
+
+func (p *mibParser) parseIndex(o *mibObject) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	index := []string{}
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.errorf("unterminated INDEX for %s", o.Name)
+		case p.is("}") || p.is(","):
+			if len(index) != 0 {
+				o.Indexes = append(o.Indexes, strings.Join(index, " "))
+				index = index[:0]
+			}
+			if p.is("}") {
+				p.advance()
+				return nil
+			}
+		case p.is("IMPLIED"):
+			o.ImpliedIndex = true
+		case p.tok.kind == tokIdent:
+			index = append(index, p.tok.text)
+		case p.is("("):
+			// Constraints on an index type, e.g. INTEGER (0..255).
+			if err := p.skipGroup(); err != nil {
+				return err
+			}
+			continue
+		default:
+			return p.errorf("unexpected %s in INDEX for %s", p.tok, o.Name)
+		}
+		p.advance()
+	}
+}
+
+// Parse a list of names such as "{ a, b }".
+func (p *mibParser) parseNameList() ([]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for !p.is("}") {
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if p.is(",") {
+			p.advance()
+		}
+	}
+	p.advance()
+	return names, nil
+}
+
+// Qualify a name with the module it is from, if that is not the current one.
+func qualifyName(module, current, name string) string {
+	if module == "" || module == current {
+		return name
+	}
+	return module + "::" + name
+}
+
+// SMIv1 traps are placed directly under their enterprise.
+func (p *mibParser) parseTrapType(m *mibModule, o *mibObject) error {
+	var err error
+	o.Kind = "TRAPTYPE"
+	enterprise := ""
+	for !p.is("::=") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.errorf("missing ::= for %s", o.Name)
+		case p.is("ENTERPRISE"):
+			p.advance()
+			if p.is("{") {
+				if o.Oid, err = p.parseOid(); err != nil {
+					return err
+				}
+				continue
+			}
+			if enterprise, err = p.expectIdent(); err != nil {
+				return err
+			}
+		case p.is("DESCRIPTION"):
+			p.advance()
+			if o.Description, err = p.expectString(); err != nil {
+				return err
+			}
+		default:
+			p.advance()
+		}
+	}
+	p.advance()
+	n, err := p.expectNumber()
+	if err != nil {
+		return err
+	}
+	if enterprise != "" {
+		o.Oid = []mibOidComponent{{Name: enterprise}}
+	}
+	if len(o.Oid) == 0 {
+		return p.errorf("missing ENTERPRISE for %s", o.Name)
+	}
+	o.Oid = append(o.Oid, mibOidComponent{Number: n, HasNumber: true})
+	m.Objects = append(m.Objects, o)
+	return nil
+}
+
+func (p *mibParser) parseOid() ([]mibOidComponent, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	oid := []mibOidComponent{}
+	for !p.is("}") {
+		var c mibOidComponent
+		switch p.tok.kind {
+		case tokNumber:
+			n, err := p.expectNumber()
+			if err != nil {
+				return nil, err
+			}
+			c.Number, c.HasNumber = n, true
+		case tokIdent:
+			c.Name = p.tok.text
+			p.advance()
+			if p.is("(") {
+				p.advance()
+				n, err := p.expectNumber()
+				if err != nil {
+					return nil, err
+				}
+				c.Number, c.HasNumber = n, true
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, p.errorf("unexpected %s in OID value", p.tok)
+		}
+		oid = append(oid, c)
+	}
+	p.advance()
+	if len(oid) == 0 {
+		return nil, p.errorf("empty OID value")
+	}
+	return oid, nil
+}
+
+func (p *mibParser) parseSyntax() (*mibSyntax, error) {
+	s := &mibSyntax{}
+	// Tags, such as "[APPLICATION 4] IMPLICIT".
+	if p.is("[") {
+		if err := p.skipGroup(); err != nil {
+			return nil, err
+		}
+		if p.is("IMPLICIT") || p.is("EXPLICIT") {
+			p.advance()
+		}
+	}
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf("expected type, found %s", p.tok)
+	}
+	name := p.tok.text
+	next := p.lookahead(1).text
+	switch {
+	case name == "OCTET" && next == "STRING":
+		p.advance()
+		s.Base = "OCTETSTR"
+	case name == "OBJECT" && next == "IDENTIFIER":
+		p.advance()
+		s.Base = "OBJID"
+	case name == "BIT" && next == "STRING", name == "BITS":
+		if name == "BIT" {
+			p.advance()
+		}
+		s.Base = "BITSTRING"
+	case name == "SEQUENCE" || name == "CHOICE":
+		p.advance()
+		s.Sequence = true
+		if p.is("OF") {
+			p.advance()
+			elem, err := p.parseSyntax()
+			if err != nil {
+				return nil, err
+			}
+			s.TypeRef = elem.TypeRef
+			return s, nil
+		}
+		if !p.is("{") {
+			return nil, p.errorf("expected \"{\" after %s, found %s", name, p.tok)
+		}
+		return s, p.skipGroup()
+	default:
+		if base, ok := smiBaseTypes[name]; ok {
+			s.Base = base
+		} else {
+			s.TypeRef = name
+		}
+	}
+	p.advance()
+
+	if p.is("{") {
+		enums, err := p.parseEnums()
+		if err != nil {
+			return nil, err
+		}
+		s.EnumValues = enums
+	}
+	if p.is("(") {
+		ranges, err := p.parseRanges()
+		if err != nil {
+			return nil, err
+		}
+		s.Ranges = ranges
+	}
+	return s, nil
+}
+
+func (p *mibParser) parseEnums() (map[int]string, error) {
+	p.advance()
+	enums := map[int]string{}
+	for !p.is("}") {
+		if p.is(",") {
+			p.advance()
+			continue
+		}
+		label, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		n, err := p.expectNumber()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		enums[int(n)] = label
+	}
+	p.advance()
+	return enums, nil
+}
+
+// Parse a value or size constraint, e.g. "(0..255)" or "(SIZE (0 | 6))".
+func (p *mibParser) parseRanges() ([]mibRange, error) {
+	p.advance()
+	if p.is("SIZE") {
+		p.advance()
+		if !p.is("(") {
+			return nil, p.errorf("expected \"(\" after SIZE, found %s", p.tok)
+		}
+		ranges, err := p.parseRanges()
+		if err != nil {
+			return nil, err
+		}
+		return ranges, p.expect(")")
+	}
+	ranges := []mibRange{}
+	for !p.is(")") {
+		switch {
+		case p.is("|"):
+			p.advance()
+			continue
+		case p.tok.kind == tokIdent:
+			// Named bounds such as MIN and MAX.
+			p.advance()
+			if p.is("..") {
+				p.advance()
+				p.advance()
+			}
+			continue
+		}
+		low, err := p.expectNumber()
+		if err != nil {
+			return nil, err
+		}
+		high := low
+		if p.is("..") {
+			p.advance()
+			if p.tok.kind == tokIdent {
+				p.advance()
+				continue
+			}
+			if high, err = p.expectNumber(); err != nil {
+				return nil, err
+			}
+		}
+		ranges = append(ranges, mibRange{Low: low, High: high})
+	}
+	p.advance()
+	return ranges, nil
+}
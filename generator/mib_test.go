@@ -0,0 +1,738 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+const testSMIMIB = `
+SNMPv2-SMI DEFINITIONS ::= BEGIN
+
+-- the path to the root
+
+org            OBJECT IDENTIFIER ::= { iso 3 }  --  "iso" = 1
+dod            OBJECT IDENTIFIER ::= { org 6 }
+internet       OBJECT IDENTIFIER ::= { dod 1 }
+mgmt           OBJECT IDENTIFIER ::= { internet 2 }
+mib-2          OBJECT IDENTIFIER ::= { mgmt 1 }
+private        OBJECT IDENTIFIER ::= { internet 4 }
+enterprises    OBJECT IDENTIFIER ::= { private 1 }
+
+OBJECT-TYPE MACRO ::=
+BEGIN
+    TYPE NOTATION ::=
+                  "SYNTAX" Syntax
+                  UnitsPart
+    VALUE NOTATION ::=
+                  value(VALUE ObjectName)
+END
+
+Counter32 ::=
+    [APPLICATION 1]
+        IMPLICIT INTEGER (0..4294967295)
+
+END
+`
+
+const testTCMIB = `
+SNMPv2-TC DEFINITIONS ::= BEGIN
+
+IMPORTS
+    TimeTicks         FROM SNMPv2-SMI;
+
+DisplayString ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "255a"
+    STATUS       current
+    DESCRIPTION
+            "Represents textual information taken from the NVT ASCII
+            character set."
+    SYNTAX       OCTET STRING (SIZE (0..255))
+
+PhysAddress ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1x:"
+    STATUS       current
+    DESCRIPTION
+            "Represents media- or physical-level addresses."
+    SYNTAX       OCTET STRING
+
+MacAddress ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1x:"
+    STATUS       current
+    DESCRIPTION
+            "Represents an 802 MAC address."
+    SYNTAX       OCTET STRING (SIZE (6))
+
+TruthValue ::= TEXTUAL-CONVENTION
+    STATUS       current
+    DESCRIPTION
+            "Represents a boolean value."
+    SYNTAX       INTEGER { true(1), false(2) }
+
+END
+`
+
+const testMIB = `
+TEST-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    MODULE-IDENTITY, OBJECT-TYPE, Integer32, Counter32, enterprises
+        FROM SNMPv2-SMI
+    DisplayString, MacAddress, TruthValue
+        FROM SNMPv2-TC
+    OBJECT-GROUP, MODULE-COMPLIANCE
+        FROM SNMPv2-CONF;
+
+testMIB MODULE-IDENTITY
+    LAST-UPDATED "202401010000Z"
+    ORGANIZATION "Prometheus"
+    CONTACT-INFO "prometheus-developers@googlegroups.com"
+    DESCRIPTION  "A MIB for testing."
+    REVISION     "202401010000Z"
+    DESCRIPTION  "Initial revision."
+    ::= { enterprises 12345 }
+
+-- Textual conventions can be local.
+Percent ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "d-2"
+    STATUS       current
+    DESCRIPTION  "A percentage."
+    SYNTAX       Integer32 (0..10000)
+
+testObjects OBJECT IDENTIFIER ::= { testMIB 1 }
+
+testScalar OBJECT-TYPE
+    SYNTAX      Integer32 (0..100)
+    UNITS       "seconds"
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "A scalar.    Ignore this."
+    DEFVAL      { 5 }
+    ::= { testObjects 1 }
+
+testTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TestEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A table."
+    ::= { testObjects 2 }
+
+testEntry OBJECT-TYPE
+    SYNTAX      TestEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A row."
+    INDEX       { testIndex, IMPLIED testName }
+    ::= { testTable 1 }
+
+TestEntry ::= SEQUENCE {
+    testIndex   Integer32,
+    testName    DisplayString,
+    testMac     MacAddress,
+    testEnabled TruthValue,
+    testStatus  INTEGER,
+    testOctets  Counter32,
+    testPercent Percent
+}
+
+testIndex OBJECT-TYPE
+    SYNTAX      Integer32 (1..2147483647)
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The index."
+    ::= { testEntry 1 }
+
+testName OBJECT-TYPE
+    SYNTAX      DisplayString (SIZE (1..32))
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The name."
+    ::= { testEntry 2 }
+
+testMac OBJECT-TYPE
+    SYNTAX      MacAddress
+    MAX-ACCESS  read-write
+    STATUS      current
+    DESCRIPTION "A MAC address."
+    ::= { testEntry 3 }
+
+testEnabled OBJECT-TYPE
+    SYNTAX      TruthValue
+    MAX-ACCESS  read-create
+    STATUS      current
+    DESCRIPTION "Whether it is enabled."
+    DEFVAL      { true }
+    ::= { testEntry 4 }
+
+testStatus OBJECT-TYPE
+    SYNTAX      INTEGER { up(1), down(2), -- Comment.
+                          testing(3) }
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The status."
+    ::= { testEntry 5 }
+
+testOctets OBJECT-TYPE
+    SYNTAX      Counter32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Octets."
+    ::= { testEntry 6 }
+
+testPercent OBJECT-TYPE
+    SYNTAX      Percent
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "Usage."
+    ::= { testEntry 7 }
+
+testAugTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TestAugEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "An augmenting table."
+    ::= { testObjects 3 }
+
+testAugEntry OBJECT-TYPE
+    SYNTAX      TestAugEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "An augmenting row."
+    AUGMENTS    { testEntry }
+    ::= { testAugTable 1 }
+
+TestAugEntry ::= SEQUENCE { testAugValue Integer32 }
+
+testAugValue OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "An augmenting value."
+    ::= { testAugEntry 1 }
+
+testGroups OBJECT IDENTIFIER ::= { testMIB 2 }
+
+testGroup OBJECT-GROUP
+    OBJECTS     { testScalar, testMac }
+    STATUS      current
+    DESCRIPTION "A group."
+    ::= { testGroups 1 }
+
+testCompliance MODULE-COMPLIANCE
+    STATUS      current
+    DESCRIPTION "A compliance statement."
+    MODULE -- this module
+        MANDATORY-GROUPS { testGroup }
+    MODULE RFC1213-MIB
+        GROUP       system
+        DESCRIPTION "Optional."
+        OBJECT      sysDescr
+        MIN-ACCESS  read-only
+        DESCRIPTION "Refinement."
+    ::= { testGroups 2 }
+
+END
+`
+
+// An SMIv1 MIB which only uses the RFC1213 DisplayString.
+const testV1MIB = `
+RFC1213-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    mgmt, NetworkAddress, IpAddress, Counter, Gauge, TimeTicks
+        FROM RFC1155-SMI
+    OBJECT-TYPE
+        FROM RFC-1212;
+
+mib-2      OBJECT IDENTIFIER ::= { mgmt 1 }
+
+DisplayString ::=
+    OCTET STRING
+
+PhysAddress ::=
+    OCTET STRING
+
+system     OBJECT IDENTIFIER ::= { mib-2 1 }
+
+sysDescr OBJECT-TYPE
+    SYNTAX  DisplayString (SIZE (0..255))
+    ACCESS  read-only
+    STATUS  mandatory
+    DESCRIPTION
+            "A textual description of the entity."
+    ::= { system 1 }
+
+sysUpTime OBJECT-TYPE
+    SYNTAX  TimeTicks
+    ACCESS  read-only
+    STATUS  mandatory
+    ::= { system 3 }
+
+atTable OBJECT-TYPE
+    SYNTAX  SEQUENCE OF AtEntry
+    ACCESS  not-accessible
+    STATUS  deprecated
+    ::= { mib-2 3 }
+
+atEntry OBJECT-TYPE
+    SYNTAX  AtEntry
+    ACCESS  not-accessible
+    STATUS  deprecated
+    INDEX   { INTEGER, atNetAddress }
+    ::= { atTable 1 }
+
+AtEntry ::=
+    SEQUENCE {
+        atPhysAddress
+            PhysAddress,
+        atNetAddress
+            NetworkAddress
+    }
+
+atPhysAddress OBJECT-TYPE
+    SYNTAX  PhysAddress
+    ACCESS  read-write
+    STATUS  deprecated
+    ::= { atEntry 2 }
+
+atNetAddress OBJECT-TYPE
+    SYNTAX  NetworkAddress
+    ACCESS  read-write
+    STATUS  deprecated
+    ::= { atEntry 3 }
+
+coldStart TRAP-TYPE
+    ENTERPRISE  snmp
+    DESCRIPTION "A cold start."
+    ::= 0
+
+snmp       OBJECT IDENTIFIER ::= { mib-2 11 }
+
+END
+`
+
+func loadTestMIBs(t *testing.T, opts string, mibs map[string]string) (*Node, []*ParseError) {
+	dir := t.TempDir()
+	for name, content := range mibs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodes, errs, err := loadMIBs([]string{dir}, opts, "", log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Error loading MIBs: %v", err)
+	}
+	return nodes, errs
+}
+
+func TestLoadMIBs(t *testing.T) {
+	nodes, errs := loadTestMIBs(t, "", map[string]string{
+		"SNMPv2-SMI": testSMIMIB,
+		"SNMPv2-TC":  testTCMIB,
+		"TEST-MIB":   testMIB,
+		"RFC1213":    testV1MIB,
+	})
+	for _, e := range errs {
+		t.Errorf("Unexpected parse error: %s", e)
+	}
+	nameToNode := map[string]*Node{}
+	walkNode(nodes, func(n *Node) {
+		nameToNode[n.Label] = n
+	})
+
+	cases := []struct {
+		label string
+		out   *Node
+	}{
+		{
+			label: "enterprises",
+			out:   &Node{Oid: "1.3.6.1.4.1", Label: "enterprises", Module: "SNMPv2-SMI", Type: "OTHER", Access: "unknown"},
+		},
+		{
+			label: "testMIB",
+			out:   &Node{Oid: "1.3.6.1.4.1.12345", Label: "testMIB", Module: "TEST-MIB", Type: "MODID", Access: "unknown", Description: "A MIB for testing."},
+		},
+		{
+			label: "testScalar",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.1", Label: "testScalar", Module: "TEST-MIB", Type: "INTEGER32", Access: "ACCESS_READONLY",
+				Units: "seconds", Description: "A scalar.    Ignore this."},
+		},
+		{
+			label: "testTable",
+			out:   &Node{Oid: "1.3.6.1.4.1.12345.1.2", Label: "testTable", Module: "TEST-MIB", Type: "OTHER", Access: "ACCESS_NOACCESS", Description: "A table."},
+		},
+		{
+			label: "testEntry",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.2.1", Label: "testEntry", Module: "TEST-MIB", Type: "OTHER", Access: "ACCESS_NOACCESS", Description: "A row.",
+				Indexes: []string{"testIndex", "testName"}, ImpliedIndex: true},
+		},
+		{
+			label: "testName",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.2.1.2", Label: "testName", Module: "TEST-MIB", Type: "OCTETSTR", Access: "ACCESS_NOACCESS", Description: "The name.",
+				Hint: "255a", TextualConvention: "DisplayString"},
+		},
+		{
+			label: "testMac",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.2.1.3", Label: "testMac", Module: "TEST-MIB", Type: "OCTETSTR", Access: "ACCESS_READWRITE", Description: "A MAC address.",
+				Hint: "1x:", TextualConvention: "MacAddress", FixedSize: 6},
+		},
+		{
+			label: "testEnabled",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.2.1.4", Label: "testEnabled", Module: "TEST-MIB", Type: "INTEGER", Access: "ACCESS_CREATE", Description: "Whether it is enabled.",
+				TextualConvention: "TruthValue", EnumValues: map[int]string{1: "true", 2: "false"}},
+		},
+		{
+			label: "testStatus",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.2.1.5", Label: "testStatus", Module: "TEST-MIB", Type: "INTEGER", Access: "ACCESS_READONLY", Description: "The status.",
+				EnumValues: map[int]string{1: "up", 2: "down", 3: "testing"}},
+		},
+		{
+			label: "testOctets",
+			out:   &Node{Oid: "1.3.6.1.4.1.12345.1.2.1.6", Label: "testOctets", Module: "TEST-MIB", Type: "COUNTER", Access: "ACCESS_READONLY", Description: "Octets."},
+		},
+		{
+			label: "testPercent",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.2.1.7", Label: "testPercent", Module: "TEST-MIB", Type: "INTEGER32", Access: "ACCESS_READONLY", Description: "Usage.",
+				Hint: "d-2", TextualConvention: "Percent"},
+		},
+		{
+			label: "testAugEntry",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.1.3.1", Label: "testAugEntry", Module: "TEST-MIB", Type: "OTHER", Access: "ACCESS_NOACCESS", Description: "An augmenting row.",
+				Augments: "testEntry"},
+		},
+		{
+			label: "testGroup",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.2.1", Label: "testGroup", Module: "TEST-MIB", Type: "OBJGROUP", Access: "unknown", Description: "A group.",
+				Members: []string{"testScalar", "testMac"}},
+		},
+		{
+			label: "testCompliance",
+			out: &Node{Oid: "1.3.6.1.4.1.12345.2.2", Label: "testCompliance", Module: "TEST-MIB", Type: "MODCOMP", Access: "unknown", Description: "A compliance statement.",
+				Members: []string{"testGroup", "RFC1213-MIB::system"}},
+		},
+		{
+			label: "sysDescr",
+			out: &Node{Oid: "1.3.6.1.2.1.1.1", Label: "sysDescr", Module: "RFC1213-MIB", Type: "OCTETSTR", Access: "ACCESS_READONLY", Description: "A textual description of the entity.",
+				TextualConvention: "DisplayString"},
+		},
+		{
+			label: "sysUpTime",
+			out:   &Node{Oid: "1.3.6.1.2.1.1.3", Label: "sysUpTime", Module: "RFC1213-MIB", Type: "TIMETICKS", Access: "ACCESS_READONLY"},
+		},
+		{
+			label: "atEntry",
+			out: &Node{Oid: "1.3.6.1.2.1.3.1", Label: "atEntry", Module: "RFC1213-MIB", Type: "OTHER", Access: "ACCESS_NOACCESS",
+				Indexes: []string{"INTEGER", "atNetAddress"}},
+		},
+		{
+			label: "atNetAddress",
+			out:   &Node{Oid: "1.3.6.1.2.1.3.1.3", Label: "atNetAddress", Module: "RFC1213-MIB", Type: "NETADDR", Access: "ACCESS_READWRITE"},
+		},
+		{
+			label: "coldStart",
+			out:   &Node{Oid: "1.3.6.1.2.1.11.0", Label: "coldStart", Module: "RFC1213-MIB", Type: "TRAPTYPE", Access: "unknown", Description: "A cold start."},
+		},
+	}
+	for _, c := range cases {
+		got, ok := nameToNode[c.label]
+		if !ok {
+			t.Errorf("Node %s not found", c.label)
+			continue
+		}
+		if c.out.EnumValues == nil {
+			c.out.EnumValues = map[int]string{}
+		}
+		// Only compare the node itself.
+		n := *got
+		n.Children = nil
+		n.subid = 0
+		if len(n.Indexes) == 0 && len(c.out.Indexes) == 0 {
+			n.Indexes, c.out.Indexes = nil, nil
+		}
+		if !reflect.DeepEqual(&n, c.out) {
+			t.Errorf("Node %s: got %+v, wanted %+v", c.label, n, *c.out)
+		}
+	}
+
+	if nodes.Oid != "1" || nodes.Label != "iso" {
+		t.Errorf("Root node: got %s %s, wanted 1 iso", nodes.Oid, nodes.Label)
+	}
+	entry := nameToNode["testEntry"]
+	labels := []string{}
+	for _, c := range entry.Children {
+		labels = append(labels, c.Label)
+	}
+	want := []string{"testIndex", "testName", "testMac", "testEnabled", "testStatus", "testOctets", "testPercent"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("Children of testEntry: got %v, wanted %v", labels, want)
+	}
+}
+
+// The parsed tree must be prepared the same way as the trees in tree_test.go.
+func TestLoadMIBsPrepareTree(t *testing.T) {
+	nodes, errs := loadTestMIBs(t, "", map[string]string{
+		"SNMPv2-SMI": testSMIMIB,
+		"SNMPv2-TC":  testTCMIB,
+		"TEST-MIB":   testMIB,
+		"RFC1213":    testV1MIB,
+	})
+	for _, e := range errs {
+		t.Errorf("Unexpected parse error: %s", e)
+	}
+	nameToNode := prepareTree(nodes, log.NewNopLogger())
+
+	cases := []struct {
+		label   string
+		typ     string
+		indexes []string
+		implied bool
+	}{
+		{label: "testName", typ: "DisplayString", indexes: []string{"testIndex", "testName"}, implied: true},
+		{label: "testMac", typ: "PhysAddress48", indexes: []string{"testIndex", "testName"}, implied: true},
+		{label: "testAugValue", typ: "INTEGER32", indexes: []string{"testIndex", "testName"}, implied: true},
+		{label: "sysDescr", typ: "DisplayString", indexes: []string{}},
+		{label: "atPhysAddress", typ: "PhysAddress48", indexes: []string{"atEntry", "atNetAddress"}},
+	}
+	for _, c := range cases {
+		n := nameToNode[c.label]
+		if n == nil {
+			t.Errorf("Node %s not found", c.label)
+			continue
+		}
+		if n.Type != c.typ || !reflect.DeepEqual(n.Indexes, c.indexes) || n.ImpliedIndex != c.implied {
+			t.Errorf("Node %s: got type %s indexes %v implied %v, wanted %s %v %v", c.label, n.Type, n.Indexes, n.ImpliedIndex, c.typ, c.indexes, c.implied)
+		}
+	}
+	if n := nameToNode["testScalar"]; n.Description != "A scalar" {
+		t.Errorf("Description of testScalar: got %q", n.Description)
+	}
+}
+
+func TestLoadMIBsErrors(t *testing.T) {
+	nodes, errs := loadTestMIBs(t, "", map[string]string{
+		"SNMPv2-SMI": testSMIMIB,
+		"TEST-MIB":   testMIB,
+		"BROKEN-MIB": `
+BROKEN-MIB DEFINITIONS ::= BEGIN
+
+brokenObject OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  sometimes
+    ::= { enterprises 1 }
+
+BrokenType ::= INTEGER { one(1) two }
+
+-- Definitions after a broken one are still loaded.
+fineObject OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    ::= { enterprises 2 }
+
+END
+`,
+	})
+	got := []string{}
+	for _, e := range errs {
+		// The directory is random.
+		e.File = filepath.Base(e.File)
+		got = append(got, fmt.Sprintf("%s object=%s oid=%s missing=%s", e, e.Object, e.Oid, e.MissingImport))
+	}
+	want := []string{
+		`BROKEN-MIB:6: error: BROKEN-MIB: unknown access "sometimes" for brokenObject object=brokenObject oid= missing=`,
+		`BROKEN-MIB:9: error: BROKEN-MIB: expected "(", found "}" object= oid= missing=`,
+		`TEST-MIB:7: error: TEST-MIB: cannot find module SNMPv2-TC, imported for DisplayString, MacAddress, TruthValue object= oid= missing=SNMPv2-TC`,
+		`TEST-MIB:71: error: TEST-MIB: unknown type DisplayString for testName object=testName oid=1.3.6.1.4.1.12345.1.2.1.2 missing=SNMPv2-TC`,
+		`TEST-MIB:78: error: TEST-MIB: unknown type MacAddress for testMac object=testMac oid=1.3.6.1.4.1.12345.1.2.1.3 missing=SNMPv2-TC`,
+		`TEST-MIB:85: error: TEST-MIB: unknown type TruthValue for testEnabled object=testEnabled oid=1.3.6.1.4.1.12345.1.2.1.4 missing=SNMPv2-TC`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse errors:\ngot:\n%s\nwanted:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	found := false
+	walkNode(nodes, func(n *Node) {
+		found = found || n.Label == "fineObject"
+	})
+	if !found {
+		t.Errorf("Object after syntax errors not loaded")
+	}
+}
+
+func TestParseErrorAffects(t *testing.T) {
+	e := &ParseError{Oid: "1.3.6.1.4.1.12345.1"}
+	cases := []struct {
+		oid     string
+		affects bool
+	}{
+		{oid: "1.3.6.1.4.1.12345.1", affects: true},
+		{oid: "1.3.6.1.4.1.12345.1.2", affects: true},
+		{oid: "1.3.6.1.4.1.12345", affects: true},
+		{oid: "1.3.6.1.4.1.12345.10", affects: false},
+		{oid: "1.3.6.1.2.1", affects: false},
+	}
+	for _, c := range cases {
+		if got := e.Affects(c.oid); got != c.affects {
+			t.Errorf("Affects(%s): got %v, wanted %v", c.oid, got, c.affects)
+		}
+	}
+	if (&ParseError{}).Affects("1") {
+		t.Errorf("Error without an object should not affect anything")
+	}
+	// Such as a syntax error in an SMIv1 module, or an unresolved parent.
+	if !(&ParseError{Object: "brokenObject"}).Affects("1.3.6.1.2.1.2") {
+		t.Errorf("Error for an object without an OID should affect everything")
+	}
+}
+
+func TestLoadMIBsNestedTypeImport(t *testing.T) {
+	_, errs := loadTestMIBs(t, "", map[string]string{
+		"SNMPv2-SMI": testSMIMIB,
+		"TC-MIB": `
+TC-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    OtherString FROM MISSING-MIB;
+
+NestedString ::= TEXTUAL-CONVENTION
+    STATUS      current
+    DESCRIPTION "Defined in terms of a type from a missing module."
+    SYNTAX      OtherString
+
+END
+`,
+		"USER-MIB": `
+USER-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    enterprises FROM SNMPv2-SMI
+    NestedString FROM TC-MIB;
+
+userObject OBJECT-TYPE
+    SYNTAX      NestedString
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "An object."
+    ::= { enterprises 1 }
+
+END
+`,
+	})
+	var e *ParseError
+	for _, pe := range errs {
+		if pe.Object == "userObject" {
+			e = pe
+		}
+	}
+	if e == nil {
+		t.Fatalf("No error for userObject in %v", errs)
+	}
+	if e.MissingImport != "MISSING-MIB" || e.Oid != "1.3.6.1.4.1.1" {
+		t.Errorf("Got missing import %q and OID %q, wanted MISSING-MIB and 1.3.6.1.4.1.1", e.MissingImport, e.Oid)
+	}
+}
+
+func TestMIBCache(t *testing.T) {
+	dir := t.TempDir()
+	mibs := map[string]string{
+		"SNMPv2-SMI": testSMIMIB,
+		"TEST-MIB":   testMIB,
+	}
+	for name, content := range mibs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cachePath := filepath.Join(t.TempDir(), "mibs.cache")
+	// Flatten the tree, as the cache doesn't keep unexported fields.
+	flatten := func(root *Node) []Node {
+		nodes := []Node{}
+		walkNode(root, func(n *Node) {
+			nodes = append(nodes, Node{Oid: n.Oid, Label: n.Label, Type: n.Type, Hint: n.Hint, TextualConvention: n.TextualConvention,
+				FixedSize: n.FixedSize, Indexes: n.Indexes, ImpliedIndex: n.ImpliedIndex, EnumValues: n.EnumValues, Description: n.Description})
+		})
+		return nodes
+	}
+
+	nodes, errs, err := loadMIBs([]string{dir}, "", cachePath, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Error loading MIBs: %v", err)
+	}
+	files, err := readMIBFiles([]string{dir}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := readMIBCache(cachePath, mibCacheKey(files, ""))
+	if err != nil {
+		t.Fatalf("Error reading MIB cache: %v", err)
+	}
+	if !reflect.DeepEqual(flatten(c.Nodes), flatten(nodes)) {
+		t.Errorf("Cached tree differs from the parsed one")
+	}
+	if !reflect.DeepEqual(c.ParseErrors, errs) {
+		t.Errorf("Cached parse errors: got %v, wanted %v", c.ParseErrors, errs)
+	}
+
+	cachedNodes, cachedErrs, err := loadMIBs([]string{dir}, "", cachePath, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Error loading MIBs: %v", err)
+	}
+	if !reflect.DeepEqual(flatten(cachedNodes), flatten(nodes)) || !reflect.DeepEqual(cachedErrs, errs) {
+		t.Errorf("Tree loaded with cache differs from the parsed one")
+	}
+
+	// Different options or MIB contents invalidate the cache.
+	if _, err := readMIBCache(cachePath, mibCacheKey(files, "c")); err == nil {
+		t.Errorf("Cache used with different MIB options")
+	}
+	files[0].content += "\n"
+	if _, err := readMIBCache(cachePath, mibCacheKey(files, "")); err == nil {
+		t.Errorf("Cache used with changed MIBs")
+	}
+}
+
+func TestMIBComments(t *testing.T) {
+	src := `
+COMMENT-MIB DEFINITIONS ::= BEGIN
+a OBJECT IDENTIFIER ::= { iso 9 } -- comment -- b OBJECT IDENTIFIER ::= { iso 10 }
+------------------------------------
+c OBJECT IDENTIFIER ::= { iso 11 } -- comment ---
+END
+`
+	cases := []struct {
+		opts   string
+		labels []string
+	}{
+		{opts: "", labels: []string{"org", "a", "b", "c"}},
+		{opts: "c", labels: []string{"org", "a", "c"}},
+	}
+	for _, c := range cases {
+		nodes, errs := loadTestMIBs(t, c.opts, map[string]string{"COMMENT-MIB": src})
+		if len(errs) != 0 {
+			t.Errorf("Unexpected parse errors with options %q: %v", c.opts, errs)
+		}
+		labels := []string{}
+		for _, n := range nodes.Children {
+			labels = append(labels, n.Label)
+		}
+		if !reflect.DeepEqual(labels, c.labels) {
+			t.Errorf("Options %q: got %v, wanted %v", c.opts, labels, c.labels)
+		}
+	}
+}
@@ -35,6 +35,18 @@ var combinedTypes = map[string]string{
 	"LldpPortId":             "LldpPortIdSubtype",
 }
 
+// Textual conventions vendors define for the 64-bit integers NET-SNMP wraps
+// in an Opaque, and the types of their metrics. Other Opaque objects can be
+// given a type with an override.
+var opaqueIntegerTypes = map[string]string{
+	"Counter64Opaque": "counter",
+	"OpaqueCounter64": "counter",
+	"Integer64":       "gauge",
+	"I64":             "gauge",
+	"Unsigned64":      "gauge",
+	"U64":             "gauge",
+}
+
 // Helper to walk MIB nodes.
 func walkNode(n *Node, f func(n *Node)) {
 	f(n)
@@ -50,6 +62,9 @@ func prepareTree(nodes *Node, logger log.Logger) map[string]*Node {
 	walkNode(nodes, func(n *Node) {
 		nameToNode[n.Oid] = n
 		nameToNode[n.Label] = n
+		if n.Module != "" {
+			nameToNode[n.Module+"::"+n.Label] = n
+		}
 	})
 
 	// Trim down description to first sentence, removing extra whitespace.
@@ -78,7 +93,10 @@ func prepareTree(nodes *Node, logger log.Logger) map[string]*Node {
 		if n.Augments == "" {
 			return
 		}
-		augmented, ok := nameToNode[n.Augments]
+		augmented, ok := nameToNode[n.Module+"::"+n.Augments]
+		if !ok {
+			augmented, ok = nameToNode[n.Augments]
+		}
 		if !ok {
 			level.Warn(logger).Log("msg", "Can't find augmenting node", "augments", n.Augments, "node", n.Label)
 			return
@@ -130,6 +148,10 @@ func prepareTree(nodes *Node, logger log.Logger) map[string]*Node {
 		if n.TextualConvention == "Float" || n.TextualConvention == "Double" {
 			n.Type = n.TextualConvention
 		}
+		// NET-SNMP Opaque 64-bit integers, decoded by the exporter.
+		if t, ok := opaqueIntegerTypes[n.TextualConvention]; ok && n.Type == "OPAQUE" {
+			n.Type = t
+		}
 
 		// Convert RFC 2579 DateAndTime textual convention to type.
 		if n.TextualConvention == "DateAndTime" {
@@ -148,17 +170,41 @@ func prepareTree(nodes *Node, logger log.Logger) map[string]*Node {
 	return nameToNode
 }
 
+// The names of overrides in the order they are applied. When several match
+// the same object, the one for its qualified name is applied last and wins.
+func overrideOrder(overrides map[string]MetricOverrides) []string {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		qi, qj := strings.Contains(names[i], "::"), strings.Contains(names[j], "::")
+		if qi != qj {
+			return qj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
 func metricType(t string) (string, bool) {
 	if _, ok := combinedTypes[t]; ok {
 		return t, true
 	}
 	switch t {
-	case "gauge", "INTEGER", "GAUGE", "TIMETICKS", "UINTEGER", "UNSIGNED32", "INTEGER32":
+	case "gauge", "INTEGER", "GAUGE", "UINTEGER", "UNSIGNED32", "INTEGER32":
 		return "gauge", true
+	case "TIMETICKS":
+		// Converted to seconds by the exporter.
+		return "TimeTicks", true
+	case "TimeTicks", "TimeTicksAsTimestamp":
+		return t, true
 	case "counter", "COUNTER", "COUNTER64":
 		return "counter", true
-	case "OctetString", "OCTETSTR", "OBJID":
+	case "OctetString", "OCTETSTR":
 		return "OctetString", true
+	case "ObjectIdentifier", "OBJID":
+		return "ObjectIdentifier", true
 	case "BITSTRING":
 		return "Bits", true
 	case "InetAddressIPv4", "IpAddr", "IPADDR", "NETADDR":
@@ -175,6 +221,27 @@ func metricType(t string) (string, bool) {
 	}
 }
 
+// The DISPLAY-HINT to render a label value with, for the types where the
+// exporter would otherwise use a generic format.
+func displayHint(typ, hint string) string {
+	if hint == "" {
+		return ""
+	}
+	switch typ {
+	case "OctetString":
+		// Octet string hints start with a length or a repeat indicator.
+		if hint[0] == '*' || (hint[0] >= '0' && hint[0] <= '9') {
+			return hint
+		}
+	case "gauge":
+		// Plain "d" is the default rendering.
+		if hint != "d" && strings.ContainsRune("dxob", rune(hint[0])) {
+			return hint
+		}
+	}
+	return ""
+}
+
 func metricAccess(a string) bool {
 	switch a {
 	case "ACCESS_READONLY", "ACCESS_READWRITE", "ACCESS_CREATE", "ACCESS_NOACCESS":
@@ -223,13 +290,31 @@ const (
 	oidSubtree
 )
 
+// Per-module changes to nodes, applied on top of the shared tree so that
+// the tree is never modified and can be used by several modules at once.
+type nodeOverlay struct {
+	types map[*Node]string
+}
+
+func newNodeOverlay() *nodeOverlay {
+	return &nodeOverlay{types: map[*Node]string{}}
+}
+
+// The type of the node, taking overrides into account.
+func (o *nodeOverlay) Type(n *Node) string {
+	if t, ok := o.types[n]; ok {
+		return t
+	}
+	return n.Type
+}
+
 // Find node in SNMP MIB tree that represents the metric.
-func getMetricNode(oid string, node *Node, nameToNode map[string]*Node) (*Node, oidMetricType) {
+func getMetricNode(oid string, node *Node, nameToNode map[string]*Node, overlay *nodeOverlay) (*Node, oidMetricType) {
 	// Check if is a known OID/name.
 	n, ok := nameToNode[oid]
 	if ok {
 		// Known node, check if OID is a valid metric or a subtree.
-		_, ok = metricType(n.Type)
+		_, ok = metricType(overlay.Type(n))
 		if ok && metricAccess(n.Access) && len(n.Indexes) == 0 {
 			return n, oidScalar
 		}
@@ -243,7 +328,7 @@ func getMetricNode(oid string, node *Node, nameToNode map[string]*Node) (*Node,
 	}
 
 	// Table instances must be a valid metric node and have an index.
-	_, ok = metricType(n.Type)
+	_, ok = metricType(overlay.Type(n))
 	ok = ok && metricAccess(n.Access)
 	if !ok || len(n.Indexes) == 0 {
 		return nil, oidNotFound
@@ -251,63 +336,260 @@ func getMetricNode(oid string, node *Node, nameToNode map[string]*Node) (*Node,
 	return n, oidInstance
 }
 
-// In the case of multiple nodes with the same label try to return the node
-// where the OID matches in every branch apart from the last one.
-func getIndexNode(lookup string, nameToNode map[string]*Node, metricOid string) *Node {
-	for _, node := range nameToNode {
-		if node.Label != lookup {
+// Expand an entry of walk into what to walk. Names are resolved to OIDs if
+// possible, MIB modules are expanded to their tables and scalars, and
+// object groups and compliance statements to their objects.
+func expandWalk(name string, node *Node, names *nameResolver, overlay *nodeOverlay, logger log.Logger) []string {
+	n, ok := names.resolve(name)
+	if !ok {
+		if oids := moduleObjects(name, node, overlay); len(oids) != 0 {
+			return oids
+		}
+		return []string{name}
+	}
+	switch n.Type {
+	case "OBJGROUP", "MODCOMP":
+		return groupObjects(n, names.nameToNode, logger, map[*Node]struct{}{})
+	}
+	return []string{n.Oid}
+}
+
+// The OIDs of the tables and scalars defined in a MIB module.
+func moduleObjects(module string, node *Node, overlay *nodeOverlay) []string {
+	oids := []string{}
+	walkNode(node, func(n *Node) {
+		if n.Module != module {
+			return
+		}
+		if _, ok := metricType(overlay.Type(n)); !ok || !metricAccess(n.Access) {
+			return
+		}
+		if len(n.Indexes) == 0 {
+			oids = append(oids, n.Oid)
+			return
+		}
+		// Walk the table rather than each column, it's the same OID minus
+		// the entry and column.
+		oid := strings.Split(n.Oid, ".")
+		if len(oid) > 2 {
+			oids = append(oids, strings.Join(oid[:len(oid)-2], "."))
+		}
+	})
+	return minimizeOids(oids)
+}
+
+// The OIDs of the objects in a group, or in the groups of a compliance statement.
+func groupObjects(group *Node, nameToNode map[string]*Node, logger log.Logger, seen map[*Node]struct{}) []string {
+	if _, ok := seen[group]; ok {
+		return nil
+	}
+	seen[group] = struct{}{}
+	oids := []string{}
+	for _, member := range group.Members {
+		// Unqualified members are from the same module as the group.
+		n, ok := nameToNode[group.Module+"::"+member]
+		if !ok {
+			n, ok = nameToNode[member]
+		}
+		if !ok {
+			level.Warn(logger).Log("msg", "Could not find member of group", "group", group.Label, "member", member)
 			continue
 		}
+		switch n.Type {
+		case "OBJGROUP", "MODCOMP":
+			oids = append(oids, groupObjects(n, nameToNode, logger, seen)...)
+		case "NOTIFGROUP":
+			// Notifications can't be walked.
+		default:
+			oids = append(oids, n.Oid)
+		}
+	}
+	return oids
+}
+
+// Check for metrics with the same name. Some MIBs reuse names for different
+// versions of their objects, so this is only an error if the name is the
+// result of an override.
+func checkMetricNames(metrics []*config.Metric, renames, prefixes map[*config.Metric]string, logger log.Logger) error {
+	byName := map[string]*config.Metric{}
+	check := func(name string, metric *config.Metric) error {
+		prev, ok := byName[name]
+		if !ok {
+			byName[name] = metric
+			return nil
+		}
+		if prev == metric {
+			return nil
+		}
+		_, renamed := renames[metric]
+		_, prevRenamed := renames[prev]
+		_, prefixed := prefixes[metric]
+		_, prevPrefixed := prefixes[prev]
+		if renamed || prevRenamed || prefixed || prevPrefixed {
+			return fmt.Errorf("metric name %s of %s collides with %s", name, metric.Oid, prev.Oid)
+		}
+		level.Warn(logger).Log("msg", "Metrics with the same name", "name", name, "oid", metric.Oid, "other_oid", prev.Oid)
+		return nil
+	}
+	for _, metric := range metrics {
+		if err := check(metric.Name, metric); err != nil {
+			return err
+		}
+		for suffix := range metric.RegexpExtracts {
+			if err := check(metric.Name+suffix, metric); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-		oid := strings.Split(metricOid, ".")
-		oidPrefix := strings.Join(oid[:len(oid)-1], ".")
+// Resolves names to nodes. Names can be OIDs, labels, or labels qualified
+// with their MIB module as MODULE::label.
+type nameResolver struct {
+	nameToNode map[string]*Node
+	// The nodes for labels defined in more than one MIB module.
+	candidates map[string][]*Node
+	warned     map[string]struct{}
+	logger     log.Logger
+}
 
-		if strings.HasPrefix(node.Oid, oidPrefix) {
-			return node
+func newNameResolver(nameToNode map[string]*Node, logger log.Logger) *nameResolver {
+	r := &nameResolver{
+		nameToNode: nameToNode,
+		candidates: map[string][]*Node{},
+		warned:     map[string]struct{}{},
+		logger:     logger,
+	}
+	for name, n := range nameToNode {
+		if strings.Contains(name, "::") {
+			r.candidates[n.Label] = append(r.candidates[n.Label], n)
+		}
+	}
+	for label, nodes := range r.candidates {
+		if len(nodes) < 2 {
+			delete(r.candidates, label)
+			continue
 		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Oid < nodes[j].Oid
+		})
+	}
+	return r
+}
+
+// Resolve a name, warning if an unqualified label is ambiguous.
+func (r *nameResolver) resolve(name string) (*Node, bool) {
+	n, ok := r.nameToNode[name]
+	if ok && n.Label == name {
+		r.checkAmbiguous(name, n)
 	}
+	return n, ok
+}
 
+// Resolve a name used by a node, such as an index, preferring the node's
+// own MIB module.
+func (r *nameResolver) resolveFrom(from *Node, name string) (*Node, bool) {
+	n, ok := r.nameToNode[from.Module+"::"+name]
+	if !ok {
+		n, ok = r.nameToNode[name]
+	}
+	return n, ok
+}
+
+// Resolve the node for a lookup of a metric. In the case of multiple nodes
+// with the same label, try to return the node where the OID matches in every
+// branch apart from the last one.
+func (r *nameResolver) resolveLookup(lookup, metricOid string) (*Node, bool) {
+	n, ok := r.nameToNode[lookup]
+	if !ok || n.Label != lookup {
+		return n, ok
+	}
+	oid := strings.Split(metricOid, ".")
+	oidPrefix := strings.Join(oid[:len(oid)-1], ".")
+	for _, c := range r.candidates[lookup] {
+		if strings.HasPrefix(c.Oid, oidPrefix) {
+			return c, true
+		}
+	}
 	// If no node matches, revert to previous behavior.
-	return nameToNode[lookup]
+	r.checkAmbiguous(lookup, n)
+	return n, true
+}
+
+func (r *nameResolver) checkAmbiguous(label string, n *Node) {
+	candidates, ok := r.candidates[label]
+	if !ok {
+		return
+	}
+	if _, ok := r.warned[label]; ok {
+		return
+	}
+	r.warned[label] = struct{}{}
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.Module+"::"+c.Label+" ("+c.Oid+")")
+	}
+	level.Warn(r.logger).Log("msg", "Ambiguous name, qualify it as MODULE::name", "name", label, "candidates", strings.Join(names, ", "), "using", n.Module+"::"+n.Label)
 }
 
 func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*Node, logger log.Logger) (*config.Module, error) {
-	out := &config.Module{}
+	out := &config.Module{
+		Encoding:             cfg.Encoding,
+		Formats:              cfg.Formats,
+		MetricRelabelConfigs: cfg.MetricRelabelConfigs,
+		StaticLabels:         cfg.StaticLabels,
+		ComputedMetrics:      cfg.ComputedMetrics,
+		MetricInfo:           cfg.MetricInfo,
+	}
 	needToWalk := map[string]struct{}{}
 	tableInstances := map[string][]string{}
+	overlay := newNodeOverlay()
+	units := map[*config.Metric]string{}
+	names := newNameResolver(nameToNode, logger)
 
 	// Apply type overrides for the current module.
-	for name, params := range cfg.Overrides {
+	overrideNames := overrideOrder(cfg.Overrides)
+	for _, name := range overrideNames {
+		params := cfg.Overrides[name]
 		if params.Type == "" {
 			continue
 		}
 		// Find node to override.
-		n, ok := nameToNode[name]
+		n, ok := names.resolve(name)
 		if !ok {
 			level.Warn(logger).Log("msg", "Could not find node to override type", "node", name)
 			continue
 		}
 		// params.Type validated at generator configuration.
-		n.Type = params.Type
+		overlay.types[n] = params.Type
+	}
+
+	// Overrides for a qualified name only apply to the metric for that node.
+	qualifiedOverrides := map[string]string{}
+	for _, name := range overrideNames {
+		if !strings.Contains(name, "::") {
+			continue
+		}
+		n, ok := names.resolve(name)
+		if !ok {
+			level.Warn(logger).Log("msg", "Could not find node to override", "node", name)
+			continue
+		}
+		qualifiedOverrides[n.Oid] = name
 	}
 
 	// Remove redundant OIDs to be walked.
 	toWalk := []string{}
-	for _, oid := range cfg.Walk {
-		// Resolve name to OID if possible.
-		n, ok := nameToNode[oid]
-		if ok {
-			toWalk = append(toWalk, n.Oid)
-		} else {
-			toWalk = append(toWalk, oid)
-		}
+	for _, name := range cfg.Walk {
+		toWalk = append(toWalk, expandWalk(name, node, names, overlay, logger)...)
 	}
 	toWalk = minimizeOids(toWalk)
 
 	// Find all top-level nodes.
 	metricNodes := map[*Node]struct{}{}
 	for _, oid := range toWalk {
-		metricNode, oidType := getMetricNode(oid, node, nameToNode)
+		metricNode, oidType := getMetricNode(oid, node, nameToNode, overlay)
 		switch oidType {
 		case oidNotFound:
 			return nil, fmt.Errorf("cannot find oid '%s' to walk", oid)
@@ -337,7 +619,7 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 	// Find all the usable metrics.
 	for _, metricNode := range metrics {
 		walkNode(metricNode, func(n *Node) {
-			t, ok := metricType(n.Type)
+			t, ok := metricType(overlay.Type(n))
 			if !ok {
 				return // Unsupported type.
 			}
@@ -356,9 +638,15 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 				EnumValues: n.EnumValues,
 			}
 
-			if cfg.Overrides[metric.Name].Ignore {
+			if cfg.Overrides[metric.Name].Ignore || cfg.Overrides[qualifiedOverrides[metric.Oid]].Ignore {
 				return // Ignored metric.
 			}
+			if t == "OctetString" {
+				metric.DisplayHint = displayHint(t, n.Hint)
+			}
+			if cfg.ConvertUnits && n.Units != "" {
+				units[metric] = n.Units
+			}
 
 			// Afi (Address family)
 			prevType := ""
@@ -366,14 +654,14 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 			prev2Type := ""
 			for count, i := range n.Indexes {
 				index := &config.Index{Labelname: i}
-				indexNode, ok := nameToNode[i]
+				indexNode, ok := names.resolveFrom(n, i)
 				if !ok {
 					level.Warn(logger).Log("msg", "Could not find index for node", "node", n.Label, "index", i)
 					return
 				}
-				index.Type, ok = metricType(indexNode.Type)
+				index.Type, ok = metricType(overlay.Type(indexNode))
 				if !ok {
-					level.Warn(logger).Log("msg", "Can't handle index type on node", "node", n.Label, "index", i, "type", indexNode.Type)
+					level.Warn(logger).Log("msg", "Can't handle index type on node", "node", n.Label, "index", i, "type", overlay.Type(indexNode))
 					return
 				}
 				index.FixedSize = indexNode.FixedSize
@@ -381,6 +669,7 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 					index.Implied = true
 				}
 				index.EnumValues = indexNode.EnumValues
+				index.DisplayHint = displayHint(index.Type, indexNode.Hint)
 
 				// Convert (InetAddressType,InetAddress) to (InetAddress)
 				if subtype, ok := combinedTypes[index.Type]; ok {
@@ -406,7 +695,7 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 
 	for _, filter := range cfg.Filters.Static {
 		for _, oid := range filter.Targets {
-			n, ok := nameToNode[oid]
+			n, ok := names.resolve(oid)
 			if ok {
 				oid = n.Oid
 			}
@@ -424,64 +713,89 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 			requiredAsIndex = append(requiredAsIndex, lookup.SourceIndexes...)
 		}
 
-		for _, lookup := range cfg.Lookups {
-			foundIndexes := 0
-			// See if all lookup indexes are present.
-			for _, index := range metric.Indexes {
-				for _, lookupIndex := range lookup.SourceIndexes {
-					if index.Labelname == lookupIndex {
-						foundIndexes++
-					}
-				}
-			}
-			if foundIndexes == len(lookup.SourceIndexes) {
-				if _, ok := nameToNode[lookup.Lookup]; !ok {
-					return nil, fmt.Errorf("unknown index '%s'", lookup.Lookup)
-				}
-				indexNode := getIndexNode(lookup.Lookup, nameToNode, metric.Oid)
-				typ, ok := metricType(indexNode.Type)
-				if !ok {
-					return nil, fmt.Errorf("unknown index type %s for %s", indexNode.Type, lookup.Lookup)
+		// Lookups can be chained in any order, apply them until none are left that can be.
+		applied := make([]bool, len(cfg.Lookups))
+		for changed := true; changed; {
+			changed = false
+			for i, lookup := range cfg.Lookups {
+				if applied[i] {
+					continue
 				}
-				l := &config.Lookup{
-					Labelname: sanitizeLabelName(indexNode.Label),
-					Type:      typ,
-					Oid:       indexNode.Oid,
-				}
-				for _, oldIndex := range lookup.SourceIndexes {
-					l.Labels = append(l.Labels, sanitizeLabelName(oldIndex))
-				}
-				metric.Lookups = append(metric.Lookups, l)
-
-				// If lookup label is used as source index in another lookup,
-				// we need to add this new label as another index.
-				for _, sourceIndex := range requiredAsIndex {
-					if sourceIndex == l.Labelname {
-						idx := &config.Index{Labelname: l.Labelname, Type: l.Type}
-						metric.Indexes = append(metric.Indexes, idx)
-						break
+				foundIndexes := 0
+				// See if all lookup indexes are present.
+				for _, index := range metric.Indexes {
+					for _, lookupIndex := range lookup.SourceIndexes {
+						if index.Labelname == lookupIndex {
+							foundIndexes++
+						}
 					}
 				}
+				if foundIndexes == len(lookup.SourceIndexes) {
+					applied[i] = true
+					changed = true
+					indexNode, ok := names.resolveLookup(lookup.Lookup, metric.Oid)
+					if !ok {
+						return nil, fmt.Errorf("unknown index '%s'", lookup.Lookup)
+					}
+					typ, ok := metricType(overlay.Type(indexNode))
+					if !ok {
+						return nil, fmt.Errorf("unknown index type %s for %s", overlay.Type(indexNode), lookup.Lookup)
+					}
+					l := &config.Lookup{
+						Labelname:   sanitizeLabelName(indexNode.Label),
+						Type:        typ,
+						Oid:         indexNode.Oid,
+						DisplayHint: displayHint(typ, indexNode.Hint),
+					}
+					if lookup.Via != "" {
+						var err error
+						l.Via, err = lookupVia(lookup, indexNode, metric.Oid, names, overlay)
+						if err != nil {
+							return nil, err
+						}
+					}
+					for _, oldIndex := range lookup.SourceIndexes {
+						l.Labels = append(l.Labels, sanitizeLabelName(oldIndex))
+					}
+					metric.Lookups = append(metric.Lookups, l)
 
-				// Make sure we walk the lookup OID(s).
-				if len(tableInstances[metric.Oid]) > 0 {
-					for _, index := range tableInstances[metric.Oid] {
-						needToWalk[indexNode.Oid+index+"."] = struct{}{}
+					// If lookup label is used as source index in another lookup,
+					// we need to add this new label as another index.
+					for _, sourceIndex := range requiredAsIndex {
+						if sourceIndex == l.Labelname {
+							idx := &config.Index{Labelname: l.Labelname, Type: l.Type}
+							metric.Indexes = append(metric.Indexes, idx)
+							break
+						}
 					}
-				} else {
-					needToWalk[indexNode.Oid] = struct{}{}
-				}
-				// We apply the same filter to metric.Oid if the lookup oid is filtered.
-				indices, found := filterMap[indexNode.Oid]
-				if found {
-					delete(needToWalk, metric.Oid)
-					for _, index := range indices {
-						needToWalk[metric.Oid+"."+index+"."] = struct{}{}
+
+					// Make sure we walk the lookup OID(s).
+					sharedIndexOid := indexNode.Oid
+					if l.Via != nil {
+						// Which rows of the lookup are needed isn't known until
+						// the scrape, so walk all of them.
+						needToWalk[indexNode.Oid] = struct{}{}
+						sharedIndexOid = l.Via.Oid
+					}
+					if len(tableInstances[metric.Oid]) > 0 {
+						for _, index := range tableInstances[metric.Oid] {
+							needToWalk[sharedIndexOid+index+"."] = struct{}{}
+						}
+					} else {
+						needToWalk[sharedIndexOid] = struct{}{}
+					}
+					// We apply the same filter to metric.Oid if the lookup oid is filtered.
+					indices, found := filterMap[sharedIndexOid]
+					if found {
+						delete(needToWalk, metric.Oid)
+						for _, index := range indices {
+							needToWalk[metric.Oid+"."+index+"."] = struct{}{}
+						}
+					}
+					if lookup.DropSourceIndexes {
+						// Avoid leaving the old labelname around.
+						toDelete = append(toDelete, lookup.SourceIndexes...)
 					}
-				}
-				if lookup.DropSourceIndexes {
-					// Avoid leaving the old labelname around.
-					toDelete = append(toDelete, lookup.SourceIndexes...)
 				}
 			}
 		}
@@ -524,21 +838,111 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 	}
 
 	// Apply module config overrides to their corresponding metrics.
-	for name, params := range cfg.Overrides {
+	renames := map[*config.Metric]string{}
+	prefixes := map[*config.Metric]string{}
+	helps := map[*config.Metric]struct{}{}
+	combinedLow := map[string]struct{}{}
+	for _, name := range overrideNames {
+		params := cfg.Overrides[name]
 		for _, metric := range out.Metrics {
-			if name == metric.Name || name == metric.Oid {
+			if name == metric.Name || name == metric.Oid || name == qualifiedOverrides[metric.Oid] {
+				if params.Combine != "" {
+					lowOid, err := combineColumns(metric, params.Combine, names)
+					if err != nil {
+						return nil, err
+					}
+					metric.Combine = lowOid
+					metric.Type = "counter"
+					combinedLow[lowOid] = struct{}{}
+					needToWalk[lowOid] = struct{}{}
+				}
 				metric.RegexpExtracts = params.RegexpExtracts
 				metric.Offset = params.Offset
 				metric.Scale = params.Scale
+				if params.Help != "" {
+					metric.Help = params.Help
+					helps[metric] = struct{}{}
+				}
+				if params.Name != "" {
+					renames[metric] = params.Name
+				}
+				if params.Prefix != "" {
+					prefixes[metric] = params.Prefix
+				}
+				metric.Encoding = params.Encoding
+				metric.Formats = params.Formats
 			}
 		}
 	}
 
+	// The low columns of combined metrics are part of the combined value.
+	if len(combinedLow) > 0 {
+		metrics := out.Metrics[:0]
+		for _, metric := range out.Metrics {
+			if _, ok := combinedLow[metric.Oid]; !ok {
+				metrics = append(metrics, metric)
+			}
+		}
+		out.Metrics = metrics
+	}
+
+	// Convert to base units, unless overridden.
+	for metric, u := range units {
+		base, ok := lookupBaseUnit(u)
+		if !ok || (metric.Type != "gauge" && metric.Type != "counter") || len(metric.RegexpExtracts) != 0 {
+			continue
+		}
+		if _, ok := renames[metric]; !ok && !strings.HasSuffix(metric.Name, base.suffix) {
+			metric.Name += base.suffix
+		}
+		metric.Unit = strings.TrimPrefix(base.suffix, "_")
+		if metric.Scale == 0 && base.scale != 1 {
+			metric.Scale = base.scale
+		}
+		if _, ok := helps[metric]; !ok {
+			metric.Help += " (units: " + u + ")"
+		}
+	}
+
+	// TimeTicks are converted to seconds by the exporter, so always get the
+	// suffix, whether or not units are converted, to not reuse the name of
+	// the hundredths of a second exported by older versions.
+	for _, metric := range out.Metrics {
+		var suffix string
+		switch metric.Type {
+		case "TimeTicks":
+			suffix = "_seconds"
+		case "TimeTicksAsTimestamp":
+			suffix = "_timestamp_seconds"
+		default:
+			continue
+		}
+		if _, ok := renames[metric]; !ok && !strings.HasSuffix(metric.Name, suffix) {
+			metric.Name += suffix
+		}
+		metric.Unit = "seconds"
+	}
+
+	// Rename metrics only now, as overrides refer to the original names.
+	for _, metric := range out.Metrics {
+		if name, ok := renames[metric]; ok {
+			metric.Name = name
+		}
+		prefix, ok := prefixes[metric]
+		if !ok {
+			prefix = cfg.MetricPrefix
+		}
+		metric.Name = prefix + metric.Name
+	}
+	if err := checkMetricNames(out.Metrics, renames, prefixes, logger); err != nil {
+		return nil, err
+	}
+
 	// Apply filters.
 	for _, filter := range cfg.Filters.Static {
 		// Delete the oid targeted by the filter, as we won't walk the whole table.
 		for _, oid := range filter.Targets {
-			n, ok := nameToNode[oid]
+			n, ok := names.resolve(oid)
 			if ok {
 				oid = n.Oid
 			}
@@ -549,7 +953,65 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 		}
 	}
 
-	out.Filters = cfg.Filters.Dynamic
+	// Resolve names in dynamic filters, as the exporter needs OIDs.
+	for _, filter := range cfg.Filters.Dynamic {
+		if n, ok := names.resolve(filter.Oid); ok {
+			filter.Oid = n.Oid
+		}
+		targets := make([]string, 0, len(filter.Targets))
+		for _, target := range filter.Targets {
+			if n, ok := names.resolve(target); ok {
+				target = n.Oid
+			}
+			targets = append(targets, target)
+		}
+		filter.Targets = targets
+		out.Filters = append(out.Filters, filter)
+	}
+
+	// Resolve the scalars used as labels, sorted by label name to make the
+	// output deterministic.
+	scalarLabels := make([]string, 0, len(cfg.ScalarLabels))
+	for labelname := range cfg.ScalarLabels {
+		scalarLabels = append(scalarLabels, labelname)
+	}
+	sort.Strings(scalarLabels)
+	for _, labelname := range scalarLabels {
+		name := cfg.ScalarLabels[labelname]
+		n, ok := names.resolve(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown scalar '%s' for label %s", name, labelname)
+		}
+		t, ok := metricType(overlay.Type(n))
+		if !ok || !metricAccess(n.Access) || len(n.Indexes) != 0 {
+			return nil, fmt.Errorf("'%s' for label %s is not a readable scalar", name, labelname)
+		}
+		scalar := &config.ScalarLabel{
+			Labelname: labelname,
+			Oid:       n.Oid + ".0",
+			Type:      t,
+		}
+		if t == "OctetString" {
+			scalar.DisplayHint = displayHint(t, n.Hint)
+		}
+		out.ScalarLabels = append(out.ScalarLabels, scalar)
+		needToWalk[scalar.Oid+"."] = struct{}{}
+	}
+
+	if cfg.ExtendCounters != nil {
+		uptimeOid := cfg.ExtendCounters.UptimeOid
+		if n, ok := names.resolve(uptimeOid); ok {
+			uptimeOid = n.Oid + ".0"
+		}
+		out.ExtendCounters = &config.ExtendCounters{UptimeOid: uptimeOid}
+	}
+	if cfg.Timestamps != nil {
+		timestamps := *cfg.Timestamps
+		if n, ok := names.resolve(timestamps.ClockOid); ok {
+			timestamps.ClockOid = n.Oid + ".0"
+		}
+		out.Timestamps = &timestamps
+	}
 
 	oids := []string{}
 	for k := range needToWalk {
@@ -566,6 +1028,55 @@ func generateConfigModule(cfg *ModuleConfig, node *Node, nameToNode map[string]*
 	return out, nil
 }
 
+// Resolve the low column of a metric combining two 32-bit columns, which
+// must have the same indexes as the metric's high column.
+func combineColumns(metric *config.Metric, low string, names *nameResolver) (string, error) {
+	highNode, ok := names.nameToNode[metric.Oid]
+	if !ok {
+		return "", fmt.Errorf("cannot find node of metric %s", metric.Name)
+	}
+	lowNode, ok := names.resolveFrom(highNode, low)
+	if !ok {
+		return "", fmt.Errorf("unknown column '%s' to combine with %s", low, metric.Name)
+	}
+	if len(lowNode.Indexes) != len(highNode.Indexes) {
+		return "", fmt.Errorf("column '%s' has different indexes than %s it is combined with", low, metric.Name)
+	}
+	for i := range lowNode.Indexes {
+		if lowNode.Indexes[i] != highNode.Indexes[i] {
+			return "", fmt.Errorf("column '%s' has different indexes than %s it is combined with", low, metric.Name)
+		}
+	}
+	return lowNode.Oid, nil
+}
+
+// Build the intermediate column of a lookup joined by value. Its value is
+// used as the single index of the lookup table.
+func lookupVia(lookup *Lookup, lookupNode *Node, metricOid string, names *nameResolver, overlay *nodeOverlay) (*config.LookupVia, error) {
+	viaNode, ok := names.resolveLookup(lookup.Via, metricOid)
+	if !ok {
+		return nil, fmt.Errorf("unknown via '%s' of lookup '%s'", lookup.Via, lookup.Lookup)
+	}
+	typ, ok := metricType(overlay.Type(viaNode))
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s of via '%s'", overlay.Type(viaNode), lookup.Via)
+	}
+	entry, ok := names.nameToNode[lookupNode.Oid[:strings.LastIndex(lookupNode.Oid, ".")]]
+	if !ok || len(entry.Indexes) != 1 {
+		return nil, fmt.Errorf("lookup '%s' via '%s' must be in a table with a single index", lookup.Lookup, lookup.Via)
+	}
+	index, ok := names.resolveFrom(entry, entry.Indexes[0])
+	if !ok {
+		return nil, fmt.Errorf("unknown index '%s' of lookup '%s'", entry.Indexes[0], lookup.Lookup)
+	}
+	return &config.LookupVia{
+		Oid:       viaNode.Oid,
+		Type:      typ,
+		FixedSize: index.FixedSize,
+		Implied:   entry.ImpliedIndex,
+	}, nil
+}
+
 var (
 	invalidLabelCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
 )
@@ -14,9 +14,12 @@
 package main
 
 import (
+	"bytes"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/snmp_exporter/config"
@@ -125,6 +128,19 @@ func TestTreePrepare(t *testing.T) {
 			in:  &Node{Oid: "1", Type: "OPAQUE", TextualConvention: "Double"},
 			out: &Node{Oid: "1", Type: "Double", TextualConvention: "Double"},
 		},
+		{
+			in:  &Node{Oid: "1", Type: "OPAQUE", TextualConvention: "OpaqueCounter64"},
+			out: &Node{Oid: "1", Type: "counter", TextualConvention: "OpaqueCounter64"},
+		},
+		{
+			in:  &Node{Oid: "1", Type: "OPAQUE", TextualConvention: "Integer64"},
+			out: &Node{Oid: "1", Type: "gauge", TextualConvention: "Integer64"},
+		},
+		// Only Opaque ones.
+		{
+			in:  &Node{Oid: "1", Type: "OCTETSTR", TextualConvention: "Integer64"},
+			out: &Node{Oid: "1", Type: "OCTETSTR", TextualConvention: "Integer64"},
+		},
 		// RFC 2579 DateAndTime.
 		{
 			in:  &Node{Oid: "1", Type: "DisplayString", TextualConvention: "DateAndTime"},
@@ -389,10 +405,11 @@ func TestGenerateConfigModule(t *testing.T) {
 						Help: " - 1.7",
 					},
 					{
-						Name: "TIMETICKS",
+						Name: "TIMETICKS_seconds",
 						Oid:  "1.8",
-						Type: "gauge",
+						Type: "TimeTicks",
 						Help: " - 1.8",
+						Unit: "seconds",
 					},
 					{
 						Name: "COUNTER64",
@@ -488,6 +505,211 @@ func TestGenerateConfigModule(t *testing.T) {
 				},
 			},
 		},
+		// Metrics renamed with overrides and a module prefix.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "node1"},
+					{Oid: "1.2", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "bsnApIfNoOfUsers"},
+					{Oid: "1.3", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "node3"},
+				}},
+			cfg: &ModuleConfig{
+				Walk:         []string{"root"},
+				MetricPrefix: "wlc_",
+				Overrides: map[string]MetricOverrides{
+					"bsnApIfNoOfUsers": MetricOverrides{Name: "ap_interface_users", Help: "Users on the AP interface."},
+					"1.3":              MetricOverrides{Prefix: "other_"},
+				},
+			},
+			out: &config.Module{
+				Walk: []string{"1"},
+				Metrics: []*config.Metric{
+					{
+						Name: "wlc_node1",
+						Oid:  "1.1",
+						Type: "gauge",
+						Help: " - 1.1",
+					},
+					{
+						Name: "wlc_ap_interface_users",
+						Oid:  "1.2",
+						Type: "gauge",
+						Help: "Users on the AP interface.",
+					},
+					{
+						Name: "other_node3",
+						Oid:  "1.3",
+						Type: "gauge",
+						Help: " - 1.3",
+					},
+				},
+			},
+		},
+		// Units converted to base units.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "uptime", Units: "centiseconds"},
+					{Oid: "1.2", Access: "ACCESS_READONLY", Type: "GAUGE", Label: "memFree", Units: "kBytes"},
+					{Oid: "1.3", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "temp", Units: "1/100 degrees"},
+					{Oid: "1.4", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "power", Units: "milliwatts"},
+					{Oid: "1.5", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "widgets", Units: "widgets"},
+					{Oid: "1.6", Access: "ACCESS_READONLY", Type: "COUNTER", Label: "octets", Units: "octets"},
+					{Oid: "1.7", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "delay", Units: "milliseconds"},
+				}},
+			cfg: &ModuleConfig{
+				Walk:         []string{"root"},
+				ConvertUnits: true,
+				Overrides: map[string]MetricOverrides{
+					"delay": MetricOverrides{Name: "delay", Scale: 1, Help: "Delay."},
+				},
+			},
+			out: &config.Module{
+				Walk: []string{"1"},
+				Metrics: []*config.Metric{
+					{
+						Name:  "uptime_seconds",
+						Oid:   "1.1",
+						Type:  "gauge",
+						Help:  " - 1.1 (units: centiseconds)",
+						Scale: 0.01,
+						Unit:  "seconds",
+					},
+					{
+						Name:  "memFree_bytes",
+						Oid:   "1.2",
+						Type:  "gauge",
+						Help:  " - 1.2 (units: kBytes)",
+						Scale: 1024,
+						Unit:  "bytes",
+					},
+					{
+						Name:  "temp_celsius",
+						Oid:   "1.3",
+						Type:  "gauge",
+						Help:  " - 1.3 (units: 1/100 degrees)",
+						Scale: 0.01,
+						Unit:  "celsius",
+					},
+					{
+						Name:  "power_watts",
+						Oid:   "1.4",
+						Type:  "gauge",
+						Help:  " - 1.4 (units: milliwatts)",
+						Scale: 0.001,
+						Unit:  "watts",
+					},
+					{
+						Name: "widgets",
+						Oid:  "1.5",
+						Type: "gauge",
+						Help: " - 1.5",
+					},
+					{
+						Name: "octets_bytes",
+						Oid:  "1.6",
+						Type: "counter",
+						Help: " - 1.6 (units: octets)",
+						Unit: "bytes",
+					},
+					{
+						Name:  "delay",
+						Oid:   "1.7",
+						Type:  "gauge",
+						Help:  "Delay.",
+						Scale: 1,
+						Unit:  "seconds",
+					},
+				},
+			},
+		},
+		// Display hints carried into the config.
+		{
+			node: &Node{Oid: "1", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Label: "table",
+						Children: []*Node{
+							{Oid: "1.1.1", Label: "tableEntry", Indexes: []string{"tableTemp"},
+								Children: []*Node{
+									{Oid: "1.1.1.1", Access: "ACCESS_READONLY", Label: "tableTemp", Type: "INTEGER32", Hint: "d-2"},
+									{Oid: "1.1.1.2", Access: "ACCESS_READONLY", Label: "tableAddr", Type: "OCTETSTR", Hint: "1d.1d.1d.1d"},
+									{Oid: "1.1.1.3", Access: "ACCESS_READONLY", Label: "tableCount", Type: "INTEGER32", Hint: "d"},
+									{Oid: "1.1.1.4", Access: "ACCESS_READONLY", Label: "tableName", Type: "OCTETSTR", Hint: "255a"},
+								}}}}}},
+			cfg: &ModuleConfig{
+				Walk: []string{"tableAddr", "tableCount", "tableName"},
+			},
+			out: &config.Module{
+				Walk: []string{"1.1.1.2", "1.1.1.3", "1.1.1.4"},
+				Metrics: []*config.Metric{
+					{
+						Name:        "tableAddr",
+						Oid:         "1.1.1.2",
+						Type:        "OctetString",
+						Help:        " - 1.1.1.2",
+						DisplayHint: "1d.1d.1d.1d",
+						Indexes: []*config.Index{
+							{Labelname: "tableTemp", Type: "gauge", DisplayHint: "d-2"},
+						},
+					},
+					{
+						Name: "tableCount",
+						Oid:  "1.1.1.3",
+						Type: "gauge",
+						Help: " - 1.1.1.3",
+						Indexes: []*config.Index{
+							{Labelname: "tableTemp", Type: "gauge", DisplayHint: "d-2"},
+						},
+					},
+					{
+						Name: "tableName",
+						Oid:  "1.1.1.4",
+						Type: "DisplayString",
+						Help: " - 1.1.1.4",
+						Indexes: []*config.Index{
+							{Labelname: "tableTemp", Type: "gauge", DisplayHint: "d-2"},
+						},
+					},
+				},
+			},
+		},
+		// Character set and formats of string values.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Access: "ACCESS_READONLY", Type: "OCTETSTR", TextualConvention: "DisplayString", Label: "node1"},
+					{Oid: "1.2", Access: "ACCESS_READONLY", Type: "OCTETSTR", TextualConvention: "DisplayString", Label: "node2"},
+				}},
+			cfg: &ModuleConfig{
+				Walk:     []string{"root"},
+				Encoding: "GBK",
+				Formats:  config.Formats{MACFormat: "dot"},
+				Overrides: map[string]MetricOverrides{
+					"node2": MetricOverrides{Encoding: "auto", Formats: config.Formats{HexFormat: "plain"}},
+				},
+			},
+			out: &config.Module{
+				Walk:     []string{"1"},
+				Encoding: "GBK",
+				Formats:  config.Formats{MACFormat: "dot"},
+				Metrics: []*config.Metric{
+					{
+						Name: "node1",
+						Oid:  "1.1",
+						Type: "DisplayString",
+						Help: " - 1.1",
+					},
+					{
+						Name:     "node2",
+						Oid:      "1.2",
+						Type:     "DisplayString",
+						Help:     " - 1.2",
+						Encoding: "auto",
+						Formats:  config.Formats{HexFormat: "plain"},
+					},
+				},
+			},
+		},
 		// Simple metric with type override.
 		{
 			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
@@ -981,6 +1203,53 @@ func TestGenerateConfigModule(t *testing.T) {
 				},
 			},
 		},
+		// Object identifier, unsigned and timeticks indexes.
+		{
+			node: &Node{Oid: "1", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Label: "objid",
+						Children: []*Node{
+							{Oid: "1.1.1", Label: "objidEntry", Indexes: []string{"objidIndex"}, ImpliedIndex: true,
+								Children: []*Node{
+									{Oid: "1.1.1.1", Access: "ACCESS_NOACCESS", Label: "objidIndex", Type: "OBJID"},
+									{Oid: "1.1.1.2", Access: "ACCESS_READONLY", Label: "objidFoo", Type: "INTEGER"}}}}},
+					{Oid: "1.2", Label: "mixed",
+						Children: []*Node{
+							{Oid: "1.2.1", Label: "mixedEntry", Indexes: []string{"unsignedIndex", "ticksIndex", "objidIndex"},
+								Children: []*Node{
+									{Oid: "1.2.1.1", Access: "ACCESS_NOACCESS", Label: "unsignedIndex", Type: "UNSIGNED32"},
+									{Oid: "1.2.1.2", Access: "ACCESS_NOACCESS", Label: "ticksIndex", Type: "TIMETICKS"},
+									{Oid: "1.2.1.3", Access: "ACCESS_READONLY", Label: "mixedFoo", Type: "OBJID"}}}}},
+				}},
+			cfg: &ModuleConfig{
+				Walk: []string{"objidFoo", "mixedFoo"},
+			},
+			out: &config.Module{
+				Walk: []string{"1.1.1.2", "1.2.1.3"},
+				Metrics: []*config.Metric{
+					{
+						Name: "objidFoo",
+						Oid:  "1.1.1.2",
+						Help: " - 1.1.1.2",
+						Type: "gauge",
+						Indexes: []*config.Index{
+							{Labelname: "objidIndex", Type: "ObjectIdentifier", Implied: true},
+						},
+					},
+					{
+						Name: "mixedFoo",
+						Oid:  "1.2.1.3",
+						Help: " - 1.2.1.3",
+						Type: "ObjectIdentifier",
+						Indexes: []*config.Index{
+							{Labelname: "unsignedIndex", Type: "gauge"},
+							{Labelname: "ticksIndex", Type: "TimeTicks"},
+							{Labelname: "objidIndex", Type: "ObjectIdentifier"},
+						},
+					},
+				},
+			},
+		},
 		// Basic table with integer index and enum_values, overridden as EnumAsInfo.
 		{
 			node: &Node{Oid: "1", Label: "root",
@@ -1032,6 +1301,63 @@ func TestGenerateConfigModule(t *testing.T) {
 			},
 		},
 
+		// Names qualified by MIB module in walk, lookups and overrides.
+		{
+			node: &Node{Oid: "1", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Label: "octet", Module: "A-MIB",
+						Children: []*Node{
+							{Oid: "1.1.1", Label: "octetEntry", Module: "A-MIB", Indexes: []string{"octetIndex"},
+								Children: []*Node{
+									{Oid: "1.1.1.1", Access: "ACCESS_READONLY", Label: "octetIndex", Module: "A-MIB", Type: "INTEGER"},
+									{Oid: "1.1.1.2", Access: "ACCESS_READONLY", Label: "octetDesc", Module: "A-MIB", Type: "OCTETSTR"},
+									{Oid: "1.1.1.3", Access: "ACCESS_READONLY", Label: "octetFoo", Module: "A-MIB", Type: "INTEGER"}}}}},
+					{Oid: "1.2", Label: "octet", Module: "B-MIB",
+						Children: []*Node{
+							{Oid: "1.2.1", Label: "octetEntry", Module: "B-MIB", Indexes: []string{"octetIndex"},
+								Children: []*Node{
+									{Oid: "1.2.1.1", Access: "ACCESS_READONLY", Label: "octetIndex", Module: "B-MIB", Type: "INTEGER"},
+									{Oid: "1.2.1.2", Access: "ACCESS_READONLY", Label: "octetDesc", Module: "B-MIB", Type: "OCTETSTR"},
+									{Oid: "1.2.1.3", Access: "ACCESS_READONLY", Label: "octetFoo", Module: "B-MIB", Type: "INTEGER"}}}}}}},
+			cfg: &ModuleConfig{
+				Walk: []string{"A-MIB::octetFoo", "B-MIB::octetFoo"},
+				Lookups: []*Lookup{
+					{
+						SourceIndexes: []string{"octetIndex"},
+						Lookup:        "B-MIB::octetDesc",
+					},
+				},
+				Overrides: map[string]MetricOverrides{
+					"B-MIB::octetFoo": MetricOverrides{Ignore: true},
+				},
+			},
+			out: &config.Module{
+				Walk: []string{"1.1.1.3", "1.2.1.2", "1.2.1.3"},
+				Metrics: []*config.Metric{
+					{
+						Name: "octetFoo",
+						Oid:  "1.1.1.3",
+						Help: " - 1.1.1.3",
+						Type: "gauge",
+						Indexes: []*config.Index{
+							{
+								Labelname: "octetIndex",
+								Type:      "gauge",
+							},
+						},
+						Lookups: []*config.Lookup{
+							{
+								Labels:    []string{"octetIndex"},
+								Labelname: "octetDesc",
+								Type:      "OctetString",
+								Oid:       "1.2.1.2",
+							},
+						},
+					},
+				},
+			},
+		},
+
 		// One table lookup, lookup not walked, labels kept.
 		{
 			node: &Node{Oid: "1", Label: "root",
@@ -1309,6 +1635,289 @@ func TestGenerateConfigModule(t *testing.T) {
 				},
 			},
 		},
+		// Static and scalar labels, and the uptime of extended counters.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Access: "ACCESS_READONLY", Type: "DisplayString", Label: "sysName"},
+					{Oid: "1.2", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "node"},
+					{Oid: "1.3", Access: "ACCESS_READONLY", Type: "TIMETICKS", Label: "sysUpTime"},
+				}},
+			cfg: &ModuleConfig{
+				Walk:           []string{"node"},
+				StaticLabels:   map[string]string{"vendor": "acme"},
+				ScalarLabels:   map[string]string{"hostname": "sysName"},
+				ExtendCounters: &config.ExtendCounters{UptimeOid: "sysUpTime"},
+			},
+			out: &config.Module{
+				Get: []string{"1.1.0", "1.2.0"},
+				Metrics: []*config.Metric{
+					{
+						Name: "node",
+						Oid:  "1.2",
+						Type: "gauge",
+						Help: " - 1.2",
+					},
+				},
+				StaticLabels: map[string]string{"vendor": "acme"},
+				ScalarLabels: []*config.ScalarLabel{
+					{Labelname: "hostname", Oid: "1.1.0", Type: "DisplayString"},
+				},
+				// The exporter gets the uptime itself.
+				ExtendCounters: &config.ExtendCounters{UptimeOid: "1.3.0"},
+			},
+		},
+		// Timestamps from a device clock given by name.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Access: "ACCESS_READONLY", Type: "DateAndTime", Label: "hrSystemDate"},
+					{Oid: "1.2", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "node"},
+				}},
+			cfg: &ModuleConfig{
+				Walk:       []string{"node"},
+				Timestamps: &config.Timestamps{Source: "device_clock", ClockOid: "hrSystemDate", MaxAge: time.Minute},
+			},
+			out: &config.Module{
+				Get: []string{"1.2.0"},
+				Metrics: []*config.Metric{
+					{
+						Name: "node",
+						Oid:  "1.2",
+						Type: "gauge",
+						Help: " - 1.2",
+					},
+				},
+				Timestamps: &config.Timestamps{Source: "device_clock", ClockOid: "1.1.0", MaxAge: time.Minute},
+			},
+		},
+		// TimeTicks in seconds, and as the timestamp of a change.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Access: "ACCESS_READONLY", Type: "TIMETICKS", Label: "sysUpTime"},
+					{Oid: "1.2", Label: "ifEntry", Indexes: []string{"ifIndex"},
+						Children: []*Node{
+							{Oid: "1.2.1", Access: "ACCESS_READONLY", Label: "ifIndex", Type: "INTEGER"},
+							{Oid: "1.2.2", Access: "ACCESS_READONLY", Label: "ifLastChange", Type: "TIMETICKS"},
+						}}}},
+			cfg: &ModuleConfig{
+				Walk:         []string{"sysUpTime", "ifLastChange"},
+				ConvertUnits: true,
+				Overrides: map[string]MetricOverrides{
+					"ifLastChange": {Type: "TimeTicksAsTimestamp"},
+				},
+			},
+			out: &config.Module{
+				Get:  []string{"1.1.0"},
+				Walk: []string{"1.2.2"},
+				Metrics: []*config.Metric{
+					{
+						Name: "sysUpTime_seconds",
+						Oid:  "1.1",
+						Type: "TimeTicks",
+						Help: " - 1.1",
+						Unit: "seconds",
+					},
+					{
+						Name:    "ifLastChange_timestamp_seconds",
+						Oid:     "1.2.2",
+						Type:    "TimeTicksAsTimestamp",
+						Help:    " - 1.2.2",
+						Indexes: []*config.Index{{Labelname: "ifIndex", Type: "gauge"}},
+						Lookups: []*config.Lookup{},
+						Unit:    "seconds",
+					},
+				},
+			},
+		},
+		// High and low 32-bit columns combined into a counter.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Label: "fooEntry", Indexes: []string{"fooIndex"},
+						Children: []*Node{
+							{Oid: "1.1.1", Access: "ACCESS_READONLY", Label: "fooIndex", Type: "INTEGER"},
+							{Oid: "1.1.2", Access: "ACCESS_READONLY", Label: "fooOctetsHigh", Type: "UNSIGNED32"},
+							{Oid: "1.1.3", Access: "ACCESS_READONLY", Label: "fooOctetsLow", Type: "UNSIGNED32"},
+						}}}},
+			cfg: &ModuleConfig{
+				Walk: []string{"fooOctetsHigh"},
+				Overrides: map[string]MetricOverrides{
+					"fooOctetsHigh": {Combine: "fooOctetsLow", Name: "fooOctets"},
+				},
+			},
+			out: &config.Module{
+				// The low column is walked too.
+				Walk: []string{"1.1.2", "1.1.3"},
+				Metrics: []*config.Metric{
+					{
+						Name:    "fooOctets",
+						Oid:     "1.1.2",
+						Type:    "counter",
+						Help:    " - 1.1.2",
+						Indexes: []*config.Index{{Labelname: "fooIndex", Type: "gauge"}},
+						Lookups: []*config.Lookup{},
+						Combine: "1.1.3",
+					},
+				},
+			},
+		},
+		// Combined columns are not output separately.
+		{
+			node: &Node{Oid: "1", Type: "OTHER", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Label: "fooEntry", Indexes: []string{"fooIndex"},
+						Children: []*Node{
+							{Oid: "1.1.1", Access: "ACCESS_READONLY", Label: "fooIndex", Type: "INTEGER"},
+							{Oid: "1.1.2", Access: "ACCESS_READONLY", Label: "fooOctetsHigh", Type: "UNSIGNED32"},
+							{Oid: "1.1.3", Access: "ACCESS_READONLY", Label: "fooOctetsLow", Type: "UNSIGNED32"},
+						}}}},
+			cfg: &ModuleConfig{
+				Walk: []string{"fooOctetsHigh", "fooOctetsLow"},
+				Overrides: map[string]MetricOverrides{
+					"fooOctetsHigh": {Combine: "fooOctetsLow"},
+				},
+			},
+			out: &config.Module{
+				Walk: []string{"1.1.2", "1.1.3"},
+				Metrics: []*config.Metric{
+					{
+						Name:    "fooOctetsHigh",
+						Oid:     "1.1.2",
+						Type:    "counter",
+						Help:    " - 1.1.2",
+						Indexes: []*config.Index{{Labelname: "fooIndex", Type: "gauge"}},
+						Lookups: []*config.Lookup{},
+						Combine: "1.1.3",
+					},
+				},
+			},
+		},
+		// Chained lookups listed out of order
+		{
+			node: &Node{Oid: "1", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Label: "octet",
+						Children: []*Node{
+							{Oid: "1.1.1", Label: "octetEntry", Indexes: []string{"octetIndex", "octetIndex2"},
+								Children: []*Node{
+									{Oid: "1.1.1.1", Access: "ACCESS_READONLY", Label: "octetIndex", Type: "INTEGER"},
+									{Oid: "1.1.1.2", Access: "ACCESS_READONLY", Label: "octetIndex2", Type: "INTEGER"},
+									{Oid: "1.1.1.3", Access: "ACCESS_READONLY", Label: "octetFoo", Type: "INTEGER"}}},
+							{Oid: "1.1.2", Label: "octetOtherEntry", Indexes: []string{"octetIndex3"},
+								Children: []*Node{
+									{Oid: "1.1.2.1", Access: "ACCESS_READONLY", Label: "octetIndex3", Type: "INTEGER"},
+									{Oid: "1.1.2.2", Access: "ACCESS_READONLY", Label: "octetDesc", Type: "OCTETSTR"}}}}}}},
+			cfg: &ModuleConfig{
+				Walk: []string{"octetFoo"},
+				Lookups: []*Lookup{
+					{
+						SourceIndexes: []string{"octetIndex3"},
+						Lookup:        "octetDesc",
+					},
+					{
+						SourceIndexes: []string{"octetIndex", "octetIndex2"},
+						Lookup:        "octetIndex3",
+					},
+				},
+			},
+			out: &config.Module{
+				// Walk is expanded to include the lookup OID.
+				Walk: []string{"1.1.1.3", "1.1.2.1", "1.1.2.2"},
+				Metrics: []*config.Metric{
+					{
+						Name: "octetFoo",
+						Oid:  "1.1.1.3",
+						Help: " - 1.1.1.3",
+						Type: "gauge",
+						Indexes: []*config.Index{
+							{
+								Labelname: "octetIndex",
+								Type:      "gauge",
+							},
+							{
+								Labelname: "octetIndex2",
+								Type:      "gauge",
+							},
+							{
+								Labelname: "octetIndex3",
+								Type:      "gauge",
+							},
+						},
+						Lookups: []*config.Lookup{
+							{
+								Labels:    []string{"octetIndex", "octetIndex2"},
+								Labelname: "octetIndex3",
+								Type:      "gauge",
+								Oid:       "1.1.2.1",
+							},
+							{
+								Labels:    []string{"octetIndex3"},
+								Labelname: "octetDesc",
+								Type:      "OctetString",
+								Oid:       "1.1.2.2",
+							},
+						},
+					},
+				},
+			},
+		},
+		// Lookup joined by the value of an intermediate column.
+		{
+			node: &Node{Oid: "1", Label: "root",
+				Children: []*Node{
+					{Oid: "1.1", Label: "basePortTable",
+						Children: []*Node{
+							{Oid: "1.1.1", Label: "basePortEntry", Indexes: []string{"basePort"},
+								Children: []*Node{
+									{Oid: "1.1.1.1", Access: "ACCESS_READONLY", Label: "basePort", Type: "INTEGER"},
+									{Oid: "1.1.1.2", Access: "ACCESS_READONLY", Label: "basePortIfIndex", Type: "INTEGER"},
+									{Oid: "1.1.1.3", Access: "ACCESS_READONLY", Label: "basePortDelay", Type: "INTEGER"}}}}},
+					{Oid: "1.2", Label: "ifTable",
+						Children: []*Node{
+							{Oid: "1.2.1", Label: "ifEntry", Indexes: []string{"ifIndex"},
+								Children: []*Node{
+									{Oid: "1.2.1.1", Access: "ACCESS_READONLY", Label: "ifIndex", Type: "INTEGER"},
+									{Oid: "1.2.1.2", Access: "ACCESS_READONLY", Label: "ifName", Type: "OCTETSTR", TextualConvention: "DisplayString"}}}}}}},
+			cfg: &ModuleConfig{
+				Walk: []string{"basePortDelay"},
+				Lookups: []*Lookup{
+					{
+						SourceIndexes: []string{"basePort"},
+						Lookup:        "ifName",
+						Via:           "basePortIfIndex",
+					},
+				},
+			},
+			out: &config.Module{
+				// Walk is expanded to include the intermediate column.
+				Walk: []string{"1.1.1.2", "1.1.1.3", "1.2.1.2"},
+				Metrics: []*config.Metric{
+					{
+						Name: "basePortDelay",
+						Oid:  "1.1.1.3",
+						Help: " - 1.1.1.3",
+						Type: "gauge",
+						Indexes: []*config.Index{
+							{
+								Labelname: "basePort",
+								Type:      "gauge",
+							},
+						},
+						Lookups: []*config.Lookup{
+							{
+								Labels:    []string{"basePort"},
+								Labelname: "ifName",
+								Type:      "DisplayString",
+								Oid:       "1.2.1.2",
+								Via:       &config.LookupVia{Oid: "1.1.1.2", Type: "gauge"},
+							},
+						},
+					},
+				},
+			},
+		},
 		// Validate metric names.
 		{
 			node: &Node{Oid: "1", Label: "root",
@@ -2009,10 +2618,18 @@ func TestGenerateConfigModule(t *testing.T) {
 		}
 
 		nameToNode := prepareTree(c.node, log.NewNopLogger())
+		types := map[*Node]string{}
+		walkNode(c.node, func(n *Node) { types[n] = n.Type })
 		got, err := generateConfigModule(c.cfg, c.node, nameToNode, log.NewNopLogger())
 		if err != nil {
 			t.Errorf("Error generating config in case %d: %s", i, err)
 		}
+		// The tree is shared between modules, so must not be modified.
+		walkNode(c.node, func(n *Node) {
+			if n.Type != types[n] {
+				t.Errorf("Type of node %s modified in case %d: got %s, wanted %s", n.Oid, i, n.Type, types[n])
+			}
+		})
 		if !reflect.DeepEqual(got, c.out) {
 			t.Errorf("GenerateConfigModule: difference in case %d", i)
 			out, _ := yaml.Marshal(got)
@@ -2022,3 +2639,306 @@ func TestGenerateConfigModule(t *testing.T) {
 		}
 	}
 }
+
+// Fixtures of TestGenerateConfigModule, with the tree parsed from a MIB.
+func TestGenerateConfigModuleFromMIB(t *testing.T) {
+	cases := []struct {
+		mib    string
+		errors int
+		cfg    *ModuleConfig
+		out    *config.Module
+	}{
+		// Basic table with integer index, after a broken definition.
+		{
+			mib: `
+TEST-MIB DEFINITIONS ::= BEGIN
+
+broken OBJECT-TYPE
+    SYNTAX  INTEGER
+    ACCESS  sometimes
+    STATUS  mandatory
+    ::= { iso 2 }
+
+table OBJECT-TYPE
+    SYNTAX  SEQUENCE OF TableEntry
+    ACCESS  not-accessible
+    STATUS  mandatory
+    ::= { iso 1 }
+
+tableEntry OBJECT-TYPE
+    SYNTAX  TableEntry
+    ACCESS  not-accessible
+    STATUS  mandatory
+    INDEX   { tableIndex }
+    ::= { table 1 }
+
+TableEntry ::= SEQUENCE { tableIndex INTEGER, tableFoo INTEGER }
+
+tableIndex OBJECT-TYPE
+    SYNTAX  INTEGER
+    ACCESS  read-only
+    STATUS  mandatory
+    ::= { tableEntry 1 }
+
+tableFoo OBJECT-TYPE
+    SYNTAX  INTEGER
+    ACCESS  read-only
+    STATUS  mandatory
+    ::= { tableEntry 2 }
+
+END
+`,
+			errors: 1,
+			cfg: &ModuleConfig{
+				Walk: []string{"table"},
+			},
+			out: &config.Module{
+				Walk: []string{"1.1"},
+				Metrics: []*config.Metric{
+					{
+						Name: "tableIndex",
+						Oid:  "1.1.1.1",
+						Type: "gauge",
+						Help: " - 1.1.1.1",
+						Indexes: []*config.Index{
+							{
+								Labelname: "tableIndex",
+								Type:      "gauge",
+							},
+						},
+					},
+					{
+						Name: "tableFoo",
+						Oid:  "1.1.1.2",
+						Type: "gauge",
+						Help: " - 1.1.1.2",
+						Indexes: []*config.Index{
+							{
+								Labelname: "tableIndex",
+								Type:      "gauge",
+							},
+						},
+					},
+				},
+			},
+		},
+		// One table lookup, lookup not walked.
+		{
+			mib: `
+TEST-MIB DEFINITIONS ::= BEGIN
+
+octet OBJECT-TYPE
+    SYNTAX  SEQUENCE OF OctetEntry
+    ACCESS  not-accessible
+    STATUS  mandatory
+    ::= { iso 1 }
+
+octetEntry OBJECT-TYPE
+    SYNTAX  OctetEntry
+    ACCESS  not-accessible
+    STATUS  mandatory
+    INDEX   { octetIndex }
+    ::= { octet 1 }
+
+OctetEntry ::= SEQUENCE { octetIndex INTEGER, octetDesc OCTET STRING, octetFoo INTEGER }
+
+octetIndex OBJECT-TYPE
+    SYNTAX  INTEGER
+    ACCESS  read-only
+    STATUS  mandatory
+    ::= { octetEntry 1 }
+
+octetDesc OBJECT-TYPE
+    SYNTAX  OCTET STRING
+    ACCESS  read-only
+    STATUS  mandatory
+    ::= { octetEntry 2 }
+
+octetFoo OBJECT-TYPE
+    SYNTAX  INTEGER
+    ACCESS  read-only
+    STATUS  mandatory
+    ::= { octetEntry 3 }
+
+END
+`,
+			cfg: &ModuleConfig{
+				Walk: []string{"octetFoo"},
+				Lookups: []*Lookup{
+					{
+						SourceIndexes:     []string{"octetIndex"},
+						Lookup:            "TEST-MIB::octetDesc",
+						DropSourceIndexes: true,
+					},
+				},
+			},
+			out: &config.Module{
+				Walk: []string{"1.1.1.2", "1.1.1.3"},
+				Metrics: []*config.Metric{
+					{
+						Name: "octetFoo",
+						Oid:  "1.1.1.3",
+						Help: " - 1.1.1.3",
+						Type: "gauge",
+						Indexes: []*config.Index{
+							{
+								Labelname: "octetIndex",
+								Type:      "gauge",
+							},
+						},
+						Lookups: []*config.Lookup{
+							{
+								Labels:    []string{"octetIndex"},
+								Labelname: "octetDesc",
+								Type:      "OctetString",
+								Oid:       "1.1.1.2",
+							},
+							{
+								Labelname: "octetIndex",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	for i, c := range cases {
+		nodes, errs := loadTestMIBs(t, "", map[string]string{"TEST-MIB": c.mib})
+		if len(errs) != c.errors {
+			t.Errorf("Case %d: got parse errors %v, wanted %d", i, errs, c.errors)
+		}
+		nameToNode := prepareTree(nodes, log.NewNopLogger())
+		got, err := generateConfigModule(c.cfg, nodes, nameToNode, log.NewNopLogger())
+		if err != nil {
+			t.Errorf("Error generating config in case %d: %s", i, err)
+		}
+		// Compare what ends up in snmp.yml, as parsed nodes always have
+		// enum values.
+		out, _ := yaml.Marshal(got)
+		want, _ := yaml.Marshal(c.out)
+		if !bytes.Equal(out, want) {
+			t.Errorf("Case %d: got:\n%s\nwanted:\n%s", i, out, want)
+		}
+	}
+}
+
+func TestGenerateConfigModuleOverrideOrder(t *testing.T) {
+	node := &Node{Oid: "1", Label: "root", Children: []*Node{
+		{Oid: "1.1", Access: "ACCESS_READONLY", Label: "ifDescr", Module: "IF-MIB", Type: "OCTETSTR"},
+	}}
+	cfg := &ModuleConfig{
+		Walk: []string{"root"},
+		Overrides: map[string]MetricOverrides{
+			"1.1":             {Help: "By OID"},
+			"ifDescr":         {Type: "OctetString", Help: "By name"},
+			"IF-MIB::ifDescr": {Type: "DisplayString", Help: "By qualified name"},
+		},
+	}
+	nameToNode := prepareTree(node, log.NewNopLogger())
+	// Map iteration order is random, so try a few times.
+	for i := 0; i < 20; i++ {
+		out, err := generateConfigModule(cfg, node, nameToNode, log.NewNopLogger())
+		if err != nil {
+			t.Fatalf("Error generating config: %s", err)
+		}
+		if m := out.Metrics[0]; m.Type != "DisplayString" || m.Help != "By qualified name" {
+			t.Fatalf("Got type %s and help %q, wanted the qualified override", m.Type, m.Help)
+		}
+	}
+}
+
+func TestExpandWalk(t *testing.T) {
+	node := &Node{Oid: "1", Label: "root", Children: []*Node{
+		{Oid: "1.1", Label: "ifMIB", Module: "IF-MIB", Type: "MODID", Children: []*Node{
+			{Oid: "1.1.1", Label: "ifNumber", Module: "IF-MIB", Access: "ACCESS_READONLY", Type: "INTEGER32"},
+			{Oid: "1.1.2", Label: "ifTable", Module: "IF-MIB", Children: []*Node{
+				{Oid: "1.1.2.1", Label: "ifEntry", Module: "IF-MIB", Indexes: []string{"ifIndex"}, Children: []*Node{
+					{Oid: "1.1.2.1.1", Label: "ifIndex", Module: "IF-MIB", Access: "ACCESS_READONLY", Type: "INTEGER32"},
+					{Oid: "1.1.2.1.2", Label: "ifDescr", Module: "IF-MIB", Access: "ACCESS_READONLY", Type: "OCTETSTR"},
+				}},
+			}},
+			{Oid: "1.1.3", Label: "ifGroups", Module: "IF-MIB", Children: []*Node{
+				{Oid: "1.1.3.1", Label: "ifGeneralGroup", Module: "IF-MIB", Type: "OBJGROUP", Members: []string{"ifNumber", "ifDescr"}},
+				{Oid: "1.1.3.2", Label: "ifTrapGroup", Module: "IF-MIB", Type: "NOTIFGROUP", Members: []string{"linkDown"}},
+				{Oid: "1.1.3.3", Label: "ifCompliance", Module: "IF-MIB", Type: "MODCOMP",
+					Members: []string{"ifGeneralGroup", "ifTrapGroup", "OTHER-MIB::otherGroup"}},
+			}},
+		}},
+		{Oid: "1.2", Label: "otherMIB", Module: "OTHER-MIB", Type: "MODID", Children: []*Node{
+			{Oid: "1.2.1", Label: "ifDescr", Module: "OTHER-MIB", Access: "ACCESS_READONLY", Type: "OCTETSTR"},
+			{Oid: "1.2.2", Label: "otherGroup", Module: "OTHER-MIB", Type: "OBJGROUP", Members: []string{"ifDescr"}},
+		}},
+	}}
+	nameToNode := prepareTree(node, log.NewNopLogger())
+
+	cases := []struct {
+		walk string
+		out  []string
+	}{
+		{walk: "1.1.2", out: []string{"1.1.2"}},
+		{walk: "ifNumber", out: []string{"1.1.1"}},
+		{walk: "IF-MIB::ifDescr", out: []string{"1.1.2.1.2"}},
+		{walk: "OTHER-MIB::ifDescr", out: []string{"1.2.1"}},
+		{walk: "IF-MIB", out: []string{"1.1.1", "1.1.2"}},
+		{walk: "OTHER-MIB", out: []string{"1.2.1"}},
+		{walk: "ifGeneralGroup", out: []string{"1.1.1", "1.1.2.1.2"}},
+		{walk: "IF-MIB::ifCompliance", out: []string{"1.1.1", "1.1.2.1.2", "1.2.1"}},
+		{walk: "1.5", out: []string{"1.5"}},
+	}
+	for _, c := range cases {
+		got := expandWalk(c.walk, node, newNameResolver(nameToNode, log.NewNopLogger()), newNodeOverlay(), log.NewNopLogger())
+		if !reflect.DeepEqual(got, c.out) {
+			t.Errorf("expandWalk(%s): got %v, wanted %v", c.walk, got, c.out)
+		}
+	}
+}
+
+func TestNameResolverAmbiguous(t *testing.T) {
+	node := &Node{Oid: "1", Label: "root", Children: []*Node{
+		{Oid: "1.1", Label: "ifDescr", Module: "IF-MIB"},
+		{Oid: "1.2", Label: "ifDescr", Module: "PAN-COMMON-MIB"},
+		{Oid: "1.3", Label: "ifName", Module: "IF-MIB"},
+	}}
+	nameToNode := prepareTree(node, log.NewNopLogger())
+	var buf bytes.Buffer
+	r := newNameResolver(nameToNode, log.NewLogfmtLogger(&buf))
+
+	if n, ok := r.resolve("IF-MIB::ifDescr"); !ok || n.Oid != "1.1" {
+		t.Errorf("Qualified name resolved to %v", n)
+	}
+	if n, ok := r.resolve("ifName"); !ok || n.Oid != "1.3" {
+		t.Errorf("Unambiguous name resolved to %v", n)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Unexpected warning: %s", buf.String())
+	}
+	if _, ok := r.resolve("ifDescr"); !ok {
+		t.Errorf("Ambiguous name not resolved")
+	}
+	want := `candidates="IF-MIB::ifDescr (1.1), PAN-COMMON-MIB::ifDescr (1.2)"`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Warning for ambiguous name: got %q, wanted it to contain %q", buf.String(), want)
+	}
+}
+
+func TestGenerateConfigModuleNameCollision(t *testing.T) {
+	node := &Node{Oid: "1", Type: "OTHER", Label: "root",
+		Children: []*Node{
+			{Oid: "1.1", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "node1"},
+			{Oid: "1.2", Access: "ACCESS_READONLY", Type: "INTEGER", Label: "node2"},
+		}}
+	cfg := &ModuleConfig{
+		Walk: []string{"root"},
+		Overrides: map[string]MetricOverrides{
+			"node2": MetricOverrides{Name: "node1"},
+		},
+	}
+	nameToNode := prepareTree(node, log.NewNopLogger())
+	_, err := generateConfigModule(cfg, node, nameToNode, log.NewNopLogger())
+	if err == nil {
+		t.Fatal("Expected error for colliding metric names")
+	}
+	if want := "metric name node1 of 1.2 collides with 1.1"; err.Error() != want {
+		t.Errorf("Error: got %q, wanted %q", err, want)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+)
+
+// How to convert a MIB unit to a Prometheus base unit.
+type baseUnit struct {
+	scale  float64
+	suffix string
+}
+
+// Common UNITS clauses, lowercased and with whitespace collapsed.
+// Abbreviations that are ambiguous once lowercased, such as "mb" for
+// megabits or megabytes and "mw" for milliwatts or megawatts, are left
+// out, so such objects are not converted.
+var baseUnits = map[string]baseUnit{
+	"seconds":                {1, "_seconds"},
+	"second":                 {1, "_seconds"},
+	"sec":                    {1, "_seconds"},
+	"secs":                   {1, "_seconds"},
+	"deciseconds":            {0.1, "_seconds"},
+	"centiseconds":           {0.01, "_seconds"},
+	"centi-seconds":          {0.01, "_seconds"},
+	"hundredths of a second": {0.01, "_seconds"},
+	"hundredths of seconds":  {0.01, "_seconds"},
+	"1/100 seconds":          {0.01, "_seconds"},
+	"0.01 seconds":           {0.01, "_seconds"},
+	"milliseconds":           {0.001, "_seconds"},
+	"millisecond":            {0.001, "_seconds"},
+	"msec":                   {0.001, "_seconds"},
+	"msecs":                  {0.001, "_seconds"},
+	"ms":                     {0.001, "_seconds"},
+	"microseconds":           {1e-6, "_seconds"},
+	"usec":                   {1e-6, "_seconds"},
+	"minutes":                {60, "_seconds"},
+	"hours":                  {3600, "_seconds"},
+	"bytes":                  {1, "_bytes"},
+	"octets":                 {1, "_bytes"},
+	"kilobytes":              {1024, "_bytes"},
+	"kbytes":                 {1024, "_bytes"},
+	"kib":                    {1024, "_bytes"},
+	"megabytes":              {1024 * 1024, "_bytes"},
+	"mbytes":                 {1024 * 1024, "_bytes"},
+	"mib":                    {1024 * 1024, "_bytes"},
+	"watts":                  {1, "_watts"},
+	"watt":                   {1, "_watts"},
+	"milliwatts":             {0.001, "_watts"},
+	"volts":                  {1, "_volts"},
+	"millivolts":             {0.001, "_volts"},
+	"amperes":                {1, "_amperes"},
+	"amps":                   {1, "_amperes"},
+	"milliamps":              {0.001, "_amperes"},
+	"milliamperes":           {0.001, "_amperes"},
+	"celsius":                {1, "_celsius"},
+	"degrees celsius":        {1, "_celsius"},
+	"degrees c":              {1, "_celsius"},
+	"degreesc":               {1, "_celsius"},
+	"1/10 degrees":           {0.1, "_celsius"},
+	"1/10 degrees celsius":   {0.1, "_celsius"},
+	"0.1 degrees celsius":    {0.1, "_celsius"},
+	"tenths of degrees":      {0.1, "_celsius"},
+	"1/100 degrees":          {0.01, "_celsius"},
+	"1/100 degrees celsius":  {0.01, "_celsius"},
+	"0.01 degrees celsius":   {0.01, "_celsius"},
+	"hundredths of degrees":  {0.01, "_celsius"},
+	"hundredths of a degree": {0.01, "_celsius"},
+	"1/1000 degrees celsius": {0.001, "_celsius"},
+	"millidegrees celsius":   {0.001, "_celsius"},
+	"hertz":                  {1, "_hertz"},
+	"hz":                     {1, "_hertz"},
+}
+
+// Find the base unit for a UNITS clause.
+func lookupBaseUnit(units string) (baseUnit, bool) {
+	u, ok := baseUnits[strings.ToLower(strings.Join(strings.Fields(units), " "))]
+	return u, ok
+}
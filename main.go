@@ -14,7 +14,9 @@
 package main
 
 import (
+	"compress/gzip"
 	"fmt"
+	"io"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -29,6 +31,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/promlog/flag"
 	"github.com/prometheus/common/version"
@@ -52,6 +55,10 @@ var (
 		"web.telemetry-path",
 		"Path under which to expose metrics.",
 	).Default("/metrics").String()
+	enableOpenMetrics = kingpin.Flag(
+		"web.enable-openmetrics",
+		"Negotiate the OpenMetrics format on /snmp, with stateset and info families, units and counter created timestamps. Counters get a _total suffix.",
+	).Default("false").Bool()
 	toolkitFlags = webflag.AddFlags(kingpin.CommandLine, ":9116")
 
 	// Metrics about the SNMP exporter itself.
@@ -142,11 +149,36 @@ func handler(w http.ResponseWriter, r *http.Request, logger log.Logger, exporter
 	registry := prometheus.NewRegistry()
 	c := collector.New(r.Context(), target, authName, auth, nmodules, logger, exporterMetrics, *concurrency)
 	registry.MustRegister(c)
+	if *enableOpenMetrics && expfmt.NegotiateIncludingOpenMetrics(r.Header) == expfmt.FmtOpenMetrics_1_0_0 {
+		serveOpenMetrics(w, r, registry, collector.OpenMetricsMetadata(nmodules), logger)
+		return
+	}
 	// Delegate http serving to Prometheus client library, which will call collector.Collect.
 	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
 	h.ServeHTTP(w, r)
 }
 
+// The client library can't express all of OpenMetrics, so it is written by
+// the collector package.
+func serveOpenMetrics(w http.ResponseWriter, r *http.Request, registry *prometheus.Registry, metadata map[string]collector.FamilyMetadata, logger log.Logger) {
+	mfs, err := registry.Gather()
+	if err != nil {
+		http.Error(w, "An error has occurred while serving metrics:\n\n"+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", string(expfmt.FmtOpenMetrics_1_0_0))
+	out := io.Writer(w)
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	if err := collector.WriteOpenMetrics(out, mfs, metadata); err != nil {
+		level.Debug(logger).Log("msg", "Error writing OpenMetrics response", "err", err)
+	}
+}
+
 func updateConfiguration(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
@@ -266,6 +298,14 @@ func main() {
 				Help:      "Number of SNMP packet retries.",
 			},
 		),
+		SNMPIndexDecodeErrors: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "index_decode_errors_total",
+				Help:      "Rows skipped because their indexes could not be decoded.",
+			},
+			[]string{"module", "metric"},
+		),
 	}
 
 	http.Handle(*metricsPath, promhttp.Handler()) // Normal metrics endpoint for SNMP exporter itself.